@@ -0,0 +1,585 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	tomlPath  = `github.com/komkom/toml`
+	directive = `//tomlgen:decode`
+)
+
+// generate returns the source of the decoders for the types
+// marked in the package in dir. The file output is skipped,
+// as it holds the code of earlier runs.
+func generate(dir string, output string) ([]byte, error) {
+
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == output {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, `source`, nil)}
+	pkg, err := conf.Check(bp.ImportPath, fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []*types.Named
+	for _, f := range files {
+		for _, name := range marked(f) {
+			t, ok := pkg.Scope().Lookup(name).Type().(*types.Named)
+			if !ok {
+				return nil, fmt.Errorf(`%v is not a named type`, name)
+			}
+			if _, ok := t.Underlying().(*types.Struct); !ok {
+				return nil, fmt.Errorf(`%v is not a struct`, name)
+			}
+			roots = append(roots, t)
+		}
+	}
+
+	if len(roots) == 0 {
+		return nil, fmt.Errorf(`no types marked with %v in %v`, directive, dir)
+	}
+
+	g := &generator{
+		pkg:       pkg,
+		imports:   map[string]string{tomlPath: `toml`},
+		queued:    make(map[*types.Named]bool),
+		finishing: make(map[types.Type]int),
+	}
+	return g.file(roots)
+}
+
+// marked returns the names of the types of f marked with
+// the directive.
+func marked(f *ast.File) []string {
+
+	var names []string
+	for _, decl := range f.Decls {
+
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+
+			doc := ts.Doc
+			if doc == nil && len(gd.Specs) == 1 {
+				doc = gd.Doc
+			}
+			if hasDirective(doc) {
+				names = append(names, ts.Name.Name)
+			}
+		}
+	}
+	return names
+}
+
+func hasDirective(doc *ast.CommentGroup) bool {
+
+	if doc == nil {
+		return false
+	}
+
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+type kind int
+
+const (
+	reflectKind kind = iota
+	stringKind
+	boolKind
+	intKind
+	uintKind
+	floatKind
+	timeKind
+	localDateTimeKind
+	localDateKind
+	localTimeKind
+	structKind
+	ptrKind
+	sliceKind
+)
+
+// value describes how the generated code decodes a type.
+type value struct {
+	kind kind
+	typ  types.Type
+	elem *value
+}
+
+type field struct {
+	key         string
+	tagged      bool
+	goPath      string
+	typ         types.Type
+	appendSlice bool
+}
+
+type generator struct {
+	pkg     *types.Package
+	buf     bytes.Buffer
+	imports map[string]string
+
+	queue  []*types.Named
+	queued map[*types.Named]bool
+
+	// finishing caches whether decoding a struct ends with
+	// defaults or validations, 1 while it is checked.
+	finishing map[types.Type]int
+}
+
+func (g *generator) file(roots []*types.Named) ([]byte, error) {
+
+	for _, t := range roots {
+
+		name := t.Obj().Name()
+		if !g.generated(t) {
+			g.printf("// DecodeTOML decodes the document by reflection, as %v\n", name)
+			g.printf("// uses tags the generated code does not handle.\n")
+			g.printf("func (x *%v) DecodeTOML(d *toml.EventDecoder) error {\n", name)
+			g.printf("return d.Reflect(x)\n}\n\n")
+			continue
+		}
+
+		g.printf("// DecodeTOML decodes the document into x.\n")
+		g.printf("func (x *%v) DecodeTOML(d *toml.EventDecoder) error {\n", name)
+		g.printf("return d.Run(x.decodeTOMLField)\n}\n\n")
+		g.enqueue(t)
+	}
+
+	for len(g.queue) > 0 {
+		t := g.queue[0]
+		g.queue = g.queue[1:]
+		g.structDecoder(t)
+	}
+
+	var paths []string
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+
+	// standard packages first
+	sort.Slice(paths, func(i, j int) bool {
+		si, sj := isStd(paths[i]), isStd(paths[j])
+		if si != sj {
+			return si
+		}
+		return paths[i] < paths[j]
+	})
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "// Code generated by tomlgen. DO NOT EDIT.\n\npackage %v\n\nimport (\n", g.pkg.Name())
+	for i, path := range paths {
+		if i > 0 && isStd(paths[i-1]) && !isStd(path) {
+			head.WriteString("\n")
+		}
+
+		name := g.imports[path]
+		if name == filepath.Base(path) {
+			fmt.Fprintf(&head, "%q\n", path)
+			continue
+		}
+		fmt.Fprintf(&head, "%v %q\n", name, path)
+	}
+	head.WriteString(")\n\n")
+
+	src, err := format.Source(append(head.Bytes(), g.buf.Bytes()...))
+	if err != nil {
+		return nil, fmt.Errorf(`formatting generated code: %v`, err)
+	}
+	return src, nil
+}
+
+func isStd(path string) bool {
+	return !strings.Contains(strings.Split(path, `/`)[0], `.`)
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) enqueue(t *types.Named) {
+	if !g.queued[t] {
+		g.queued[t] = true
+		g.queue = append(g.queue, t)
+	}
+}
+
+func (g *generator) structDecoder(t *types.Named) {
+
+	name := t.Obj().Name()
+	set := `tomlFields` + strings.ToUpper(name[:1]) + name[1:]
+	fs, _ := fields(t.Underlying().(*types.Struct), ``)
+
+	g.printf("var %v = toml.FieldSet{\n", set)
+	for _, f := range fs {
+		if f.tagged {
+			g.printf("{Name: %q, Tagged: true},\n", f.key)
+			continue
+		}
+		g.printf("{Name: %q},\n", f.key)
+	}
+	g.printf("}\n\n")
+
+	g.printf("func (x *%v) decodeTOMLField(d *toml.EventDecoder, key string) error {\n\n", name)
+	g.printf("switch %v.Match(key) {\n", set)
+	for i, f := range fs {
+		g.printf("case %v:\n", i)
+		g.decode(g.value(f.typ), `x.`+f.goPath, f.appendSlice, 0)
+	}
+	g.printf("}\nreturn d.Unknown(key)\n}\n\n")
+}
+
+// decode writes the statements decoding the current value
+// into target.
+func (g *generator) decode(v value, target string, appendSlice bool, depth int) {
+
+	switch v.kind {
+	case reflectKind:
+		g.printf("return d.Reflect(&%v)\n", target)
+
+	case stringKind:
+		g.scalar(target, v.typ, `d.StringValue()`, types.Typ[types.String])
+	case boolKind:
+		g.scalar(target, v.typ, `d.BoolValue()`, types.Typ[types.Bool])
+	case intKind:
+		g.scalar(target, v.typ, fmt.Sprintf(`d.IntValue(%q, %v)`, g.reflectName(v.typ), bits(v.typ)), types.Typ[types.Int64])
+	case uintKind:
+		g.scalar(target, v.typ, fmt.Sprintf(`d.UintValue(%q, %v)`, g.reflectName(v.typ), bits(v.typ)), types.Typ[types.Uint64])
+	case floatKind:
+		g.scalar(target, v.typ, fmt.Sprintf(`d.FloatValue(%q, %v)`, g.reflectName(v.typ), bits(v.typ)), types.Typ[types.Float64])
+	case timeKind:
+		g.scalar(target, v.typ, `d.TimeValue()`, v.typ)
+	case localDateTimeKind:
+		g.scalar(target, v.typ, `d.LocalDateTimeValue()`, v.typ)
+	case localDateKind:
+		g.scalar(target, v.typ, `d.LocalDateValue()`, v.typ)
+	case localTimeKind:
+		g.scalar(target, v.typ, `d.LocalTimeValue()`, v.typ)
+
+	case structKind:
+		g.enqueue(v.typ.(*types.Named))
+		g.printf("return d.Table(%v.decodeTOMLField)\n", target)
+
+	case ptrKind:
+		g.printf("if %v == nil {\n%v = new(%v)\n}\n", target, target, g.typeName(v.elem.typ))
+		if v.elem.kind == structKind {
+			g.decode(*v.elem, target, false, depth)
+			return
+		}
+		if v.elem.kind == sliceKind {
+			g.decode(*v.elem, `(*`+target+`)`, false, depth)
+			return
+		}
+		g.decode(*v.elem, `*`+target, false, depth)
+
+	case sliceKind:
+		n, i := fmt.Sprintf(`n%v`, depth), fmt.Sprintf(`i%v`, depth)
+		typ := g.typeName(v.typ)
+
+		g.printf("return d.Slice(%v, len(%v), func(%v int) {\n", appendSlice, target, n)
+		g.printf("if %v <= len(%v) {\n", n, target)
+		g.printf("%v = append(make(%v, 0, %v), %v[:%v]...)\nreturn\n}\n", target, typ, n, target, n)
+		g.printf("%v = append(%v, make(%v, %v-len(%v))...)\n", target, target, typ, n, target)
+		g.printf("}, func(%v int) error {\n", i)
+		g.decode(*v.elem, fmt.Sprintf(`%v[%v]`, target, i), false, depth+1)
+		g.printf("})\n")
+	}
+}
+
+// scalar writes the call of method and stores its result
+// into target, converting from the type the method returns.
+func (g *generator) scalar(target string, t types.Type, method string, returned types.Type) {
+
+	conv := `v`
+	if !types.Identical(t, returned) {
+		conv = g.typeName(t) + `(v)`
+	}
+
+	g.printf("v, err := %v\nif err != nil {\nreturn err\n}\n", method)
+	g.printf("%v = %v\nreturn nil\n", target, conv)
+}
+
+// value returns how values of t are decoded.
+func (g *generator) value(t types.Type) value {
+
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+		path, name := named.Obj().Pkg().Path(), named.Obj().Name()
+
+		switch {
+		case path == `time` && name == `Time`:
+			return value{kind: timeKind, typ: t}
+		case path == tomlPath && name == `LocalDateTime`:
+			return value{kind: localDateTimeKind, typ: t}
+		case path == tomlPath && name == `LocalDate`:
+			return value{kind: localDateKind, typ: t}
+		case path == tomlPath && name == `LocalTime`:
+			return value{kind: localTimeKind, typ: t}
+		case path == tomlPath:
+			return value{kind: reflectKind, typ: t}
+		}
+	}
+
+	if custom(t) {
+		return value{kind: reflectKind, typ: t}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return value{kind: stringKind, typ: t}
+		case u.Info()&types.IsBoolean != 0:
+			return value{kind: boolKind, typ: t}
+		case u.Info()&types.IsUnsigned != 0:
+			return value{kind: uintKind, typ: t}
+		case u.Info()&types.IsInteger != 0:
+			return value{kind: intKind, typ: t}
+		case u.Info()&types.IsFloat != 0:
+			return value{kind: floatKind, typ: t}
+		}
+
+	case *types.Struct:
+		if g.generated(t) {
+			return value{kind: structKind, typ: t}
+		}
+
+	case *types.Pointer:
+		elem := g.value(u.Elem())
+		if elem.kind != reflectKind && elem.kind != ptrKind {
+			return value{kind: ptrKind, typ: t, elem: &elem}
+		}
+
+	case *types.Slice:
+		elem := g.value(u.Elem())
+		if elem.kind != reflectKind {
+			return value{kind: sliceKind, typ: t, elem: &elem}
+		}
+	}
+
+	return value{kind: reflectKind, typ: t}
+}
+
+// generated reports whether the struct type t gets a
+// generated decoder. These are the named structs of the
+// package whose decoding does not end with defaults or
+// validations.
+func (g *generator) generated(t types.Type) bool {
+
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() != g.pkg || custom(t) {
+		return false
+	}
+
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+
+	_, ok = fields(st, ``)
+	return ok && !g.finishes(t)
+}
+
+// finishes reports whether decoding the struct t ends with
+// defaults or validations, which also run for the structs
+// nested in it that are missing from the document.
+func (g *generator) finishes(t types.Type) bool {
+
+	switch g.finishing[t] {
+	case 1:
+		return false
+	case 2:
+		return true
+	case 3:
+		return false
+	}
+	g.finishing[t] = 1
+
+	res := false
+	if st, ok := t.Underlying().(*types.Struct); ok {
+		fs, _ := fields(st, ``)
+		for _, f := range fs {
+			if _, ok := f.typ.Underlying().(*types.Struct); ok && g.finishes(f.typ) {
+				res = true
+			}
+		}
+		if hasFinishingTags(st) {
+			res = true
+		}
+	}
+
+	g.finishing[t] = 3
+	if res {
+		g.finishing[t] = 2
+	}
+	return res
+}
+
+func hasFinishingTags(st *types.Struct) bool {
+
+	for i := 0; i < st.NumFields(); i++ {
+
+		tag := reflect.StructTag(st.Tag(i))
+		if _, ok := tag.Lookup(`default`); ok {
+			return true
+		}
+		if tag.Get(`validate`) != `` {
+			return true
+		}
+
+		if st.Field(i).Embedded() {
+			if est, ok := st.Field(i).Type().Underlying().(*types.Struct); ok && hasFinishingTags(est) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fields returns the fields of st the way the decoder sees
+// them, with embedded structs flattened. It fails for remain
+// fields.
+func fields(st *types.Struct, prefix string) ([]field, bool) {
+
+	var fs []field
+	for i := 0; i < st.NumFields(); i++ {
+
+		v := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+
+		name, ok := tag.Lookup(`toml`)
+		if !ok {
+			name = tag.Get(`json`)
+		}
+		if name == `-` {
+			continue
+		}
+
+		var opts string
+		if idx := strings.Index(name, `,`); idx >= 0 {
+			name, opts = name[:idx], name[idx+1:]
+		}
+
+		if v.Embedded() && name == `` {
+			if est, ok := v.Type().(*types.Named); ok {
+				if st, ok := est.Underlying().(*types.Struct); ok {
+					embedded, ok := fields(st, prefix+v.Name()+`.`)
+					if !ok {
+						return nil, false
+					}
+					fs = append(fs, embedded...)
+					continue
+				}
+			}
+		}
+
+		if !v.Exported() {
+			continue
+		}
+
+		if hasOption(opts, `remain`) {
+			return nil, false
+		}
+
+		f := field{key: name, tagged: name != ``, goPath: prefix + v.Name(), typ: v.Type(), appendSlice: hasOption(opts, `append`)}
+		if f.key == `` {
+			f.key = v.Name()
+		}
+		fs = append(fs, f)
+	}
+	return fs, true
+}
+
+func hasOption(opts string, name string) bool {
+	for _, o := range strings.Split(opts, `,`) {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// custom reports whether t decodes itself.
+func custom(t types.Type) bool {
+
+	ms := types.NewMethodSet(types.NewPointer(t))
+	for _, name := range []string{`UnmarshalTOML`, `UnmarshalText`} {
+		if ms.Lookup(nil, name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName renders t for the generated file, adding the
+// imports it needs.
+func (g *generator) typeName(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ``
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+// reflectName returns the name reflect gives t.
+func (g *generator) reflectName(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		return p.Name()
+	})
+}
+
+// bits returns the size of integer types, 0 for int and uint.
+func bits(t types.Type) int {
+
+	switch t.Underlying().(*types.Basic).Kind() {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int32, types.Uint32, types.Float32:
+		return 32
+	case types.Int64, types.Uint64, types.Float64:
+		return 64
+	}
+	return 0
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_upToDate(t *testing.T) {
+
+	dir := filepath.Join(`internal`, `example`)
+
+	src, err := generate(dir, `toml_decode.go`)
+	require.NoError(t, err)
+
+	committed, err := ioutil.ReadFile(filepath.Join(dir, `toml_decode.go`))
+	require.NoError(t, err)
+
+	assert.Equal(t, string(committed), string(src), `run go generate in %v`, dir)
+}
+
+func TestGenerate_noTypes(t *testing.T) {
+	_, err := generate(`.`, `toml_decode.go`)
+	assert.EqualError(t, err, `no types marked with //tomlgen:decode in .`)
+}
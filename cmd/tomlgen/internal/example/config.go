@@ -0,0 +1,80 @@
+// Package example holds the types the tests of tomlgen
+// generate decoders for.
+package example
+
+import (
+	"strings"
+	"time"
+
+	"github.com/komkom/toml"
+)
+
+//go:generate go run github.com/komkom/toml/cmd/tomlgen
+
+// Config uses most of what the generated code handles.
+//
+//tomlgen:decode
+type Config struct {
+	Title   string
+	Level   Level `toml:"level"`
+	Port    uint16
+	Offset  int8
+	Ratio   float32
+	Enabled *bool
+	Timeout time.Duration
+	Started time.Time
+	Since   toml.LocalDate
+
+	Owner   Owner
+	Backup  *Server
+	Servers []Server `toml:"servers"`
+	Tags    []string `toml:",append"`
+	Matrix  [][]int
+
+	Labels map[string]string
+	Name   Name
+	Any    interface{}
+	Inline struct{ Value int }
+
+	Base
+	Skipped string `toml:"-"`
+	hidden  string
+}
+
+// Level is a named integer.
+type Level int
+
+// Owner is a nested table.
+type Owner struct {
+	Name string `json:"name"`
+	Dob  toml.LocalDateTime
+}
+
+// Server is the element of an array of tables.
+type Server struct {
+	Host  string
+	Ports []int
+	Meta  map[string]interface{}
+}
+
+// Base is embedded into Config.
+type Base struct {
+	Version string
+}
+
+// Name decodes itself.
+type Name string
+
+// UnmarshalText upper cases the name.
+func (n *Name) UnmarshalText(text []byte) error {
+	*n = Name(strings.ToUpper(string(text)))
+	return nil
+}
+
+// Defaults is decoded by reflection as it uses default tags.
+//
+//tomlgen:decode
+type Defaults struct {
+	Host string `default:"localhost"`
+	Port int    `validate:"min=1"`
+}
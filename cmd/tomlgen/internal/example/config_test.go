@@ -0,0 +1,191 @@
+package example
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/komkom/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reflected has the fields of Config but no DecodeTOML
+// method, so it is decoded by reflection.
+type reflected Config
+
+const doc = `
+title = "generated"
+level = 3
+port = 8080
+offset = -128
+ratio = 2
+enabled = true
+timeout = 1000
+started = 1979-05-27T07:32:00Z
+since = "1979-05-27"
+version = "1.2"
+unknown = "skipped"
+name = "tom"
+any = [1, "two"]
+inline = { value = 7 }
+tags = ["c"]
+matrix = [[1, 2], [], [3]]
+
+labels.a = "x"
+owner = { name = "Tom", dob = 1979-05-27T07:32:00 }
+
+[backup]
+host = "back\\up"
+
+[[servers]]
+host = "alpha"
+ports = [80, 443]
+
+[servers.meta]
+a = 1
+
+[[servers]]
+host = "beta"
+meta = { b = [true] }
+
+[labels]
+b = "y"
+
+[[servers]]
+[servers.meta.deep]
+c = 1979-05-27
+`
+
+func TestGenerated_decode(t *testing.T) {
+
+	enabled := false
+	prefilled := func() Config {
+		return Config{
+			Title:   "old",
+			Enabled: &enabled,
+			Tags:    []string{"a", "b"},
+			Servers: []Server{{Host: "old", Ports: []int{1}}},
+			Labels:  map[string]string{"z": "kept"},
+		}
+	}
+
+	gen := prefilled()
+	genMeta, err := toml.NewDecoder(strings.NewReader(doc)).Decode(&gen)
+	require.NoError(t, err)
+
+	refl := reflected(prefilled())
+	reflMeta, err := toml.NewDecoder(strings.NewReader(doc)).Decode(&refl)
+	require.NoError(t, err)
+
+	assert.Equal(t, Config(refl), gen)
+	assert.Equal(t, reflMeta.Keys(), genMeta.Keys())
+	assert.Equal(t, reflMeta.Undecoded(), genMeta.Undecoded())
+	for _, k := range reflMeta.Keys() {
+		assert.Equal(t, reflMeta.Type(k...), genMeta.Type(k...), k.String())
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, gen.Tags)
+	assert.Equal(t, Name("TOM"), gen.Name)
+	assert.Equal(t, map[string]string{"a": "x", "b": "y", "z": "kept"}, gen.Labels)
+	assert.Len(t, gen.Servers, 3)
+	assert.Equal(t, []toml.Key{{"unknown"}}, genMeta.Undecoded())
+}
+
+func TestGenerated_errors(t *testing.T) {
+
+	tests := []struct {
+		doc    string
+		strict bool
+	}{
+		{doc: `title = 1`},
+		{doc: `port = -1`},
+		{doc: `port = 65536`},
+		{doc: `offset = 128`},
+		{doc: `ratio = "x"`},
+		{doc: `enabled = 1`},
+		{doc: `started = 1979-05-27`},
+		{doc: `since = "may"`},
+		{doc: `[title]`},
+		{doc: `title.x = 1`},
+		{doc: `servers = 1`},
+		{doc: `[servers]`},
+		{doc: `servers = [1]`},
+		{doc: `matrix = [[1], ["x"]]`},
+		{doc: `owner = { name = 1 }`},
+		{doc: "[owner]\nname = true"},
+		{doc: "[[servers]]\nports = [1, 2.5]"},
+		{doc: "[[servers]]\n[[servers]]\n[servers.meta]\n[labels]\na = 1"},
+		{doc: `inline = { value = "x" }`},
+		{doc: `name = 1`},
+		{doc: `unknown = 1`, strict: true},
+		{doc: "[owner]\nage = 1", strict: true},
+		{doc: `title = "x`},
+		{doc: `level = 99999999999999999999`},
+		{doc: `ratio = 1e999`},
+		{doc: `ratio = 1e300`},
+		{doc: `title.x = 99999999999999999999`},
+		{doc: `title = "a\u00"`},
+		{doc: "offset = 300\n" + strings.Repeat("# pad\n", 1000) + "= bad"},
+		{doc: "[[servers]]\nhost = 1\n" + strings.Repeat("# pad\n", 1000) + "= bad"},
+		{doc: "offset = 300\n" + strings.Repeat("# pad\n", 1000) + "level = 99999999999999999999"},
+		{doc: "offset = 300\n" + strings.Repeat("# pad\n", 1000)},
+	}
+
+	for _, test := range tests {
+
+		gen := toml.NewDecoder(strings.NewReader(test.doc))
+		refl := toml.NewDecoder(strings.NewReader(test.doc))
+		if test.strict {
+			gen.DisallowUnknownFields()
+			refl.DisallowUnknownFields()
+		}
+
+		var c Config
+		_, genErr := gen.Decode(&c)
+		require.Error(t, genErr, test.doc)
+
+		var r reflected
+		_, reflErr := refl.Decode(&r)
+		require.Error(t, reflErr, test.doc)
+
+		assert.Equal(t, reflErr.Error(), genErr.Error(), test.doc)
+	}
+}
+
+func TestGenerated_location(t *testing.T) {
+
+	doc := `started = 1979-05-27T07:32:00`
+
+	dec := toml.NewDecoder(strings.NewReader(doc))
+	dec.UseLocation(time.UTC)
+
+	var c Config
+	_, err := dec.Decode(&c)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC), c.Started)
+}
+
+func TestGenerated_reflection(t *testing.T) {
+
+	var d Defaults
+	require.NoError(t, toml.Unmarshal([]byte(`port = 80`), &d))
+	assert.Equal(t, Defaults{Host: "localhost", Port: 80}, d)
+
+	err := toml.Unmarshal([]byte(`port = 0`), &d)
+	assert.EqualError(t, err, `toml: port (line 1, col 8): value must be at least 1, got 0`)
+}
+
+func BenchmarkGenerated(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var c Config
+		_ = toml.Unmarshal([]byte(doc), &c)
+	}
+}
+
+func BenchmarkReflection(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var c reflected
+		_ = toml.Unmarshal([]byte(doc), &c)
+	}
+}
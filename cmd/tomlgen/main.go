@@ -0,0 +1,64 @@
+// Command tomlgen writes DecodeTOML methods for struct types,
+// so toml.Decoder fills them from the events of the parser
+// instead of by reflection.
+//
+// Types are picked by a directive in their doc comment:
+//
+//	//tomlgen:decode
+//	type Config struct {
+//		...
+//	}
+//
+// and the methods are generated by running tomlgen in the
+// package directory, usually from a go:generate line:
+//
+//	//go:generate go run github.com/komkom/toml/cmd/tomlgen
+//
+// The generated code decodes like the reflection decoder and
+// returns the same errors. It saves the reflection and reads
+// top level scalars without building their nodes, but parsing
+// the document costs the same, so decoding gets only somewhat
+// faster. Fields it cannot handle, like maps, interfaces,
+// anonymous structs and types implementing toml.Unmarshaler or
+// encoding.TextUnmarshaler, are decoded by reflection. Structs
+// using default, validate or remain tags are decoded by
+// reflection as a whole.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+
+	output := flag.String(`output`, `toml_decode.go`, `name of the generated file`)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: tomlgen [-output file] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := `.`
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	err := run(dir, *output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tomlgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, output string) error {
+
+	src, err := generate(dir, output)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, output), src, 0644)
+}
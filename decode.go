@@ -0,0 +1,445 @@
+package toml
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// Unmarshal parses the TOML document in data and stores
+// the result in the value pointed to by v.
+func Unmarshal(data []byte, v interface{}) error {
+	_, err := NewDecoder(bytes.NewReader(data)).Decode(v)
+	return err
+}
+
+// Unmarshaler is implemented by types that decode themselves
+// from a TOML value. The value has the types DecodeTree
+// returns, so whole tables arrive as map[string]interface{}
+// and arrays as []interface{}.
+type Unmarshaler interface {
+	UnmarshalTOML(value interface{}) error
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	localDateType     = reflect.TypeOf(LocalDate{})
+	localTimeType     = reflect.TypeOf(LocalTime{})
+	localDateTimeType = reflect.TypeOf(LocalDateTime{})
+)
+
+// A Decoder reads and decodes a TOML document
+// from an input stream.
+type Decoder struct {
+	reader   io.Reader
+	location *time.Location
+	strict   bool
+	hooks    map[reflect.Type][]Hook
+	src      *source
+
+	// violations collects the failed validations
+	// of a decodeValue call.
+	violations []*DecodeError
+}
+
+// NewDecoder returns a new decoder that reads from reader.
+func NewDecoder(reader io.Reader) *Decoder {
+	return &Decoder{reader: reader}
+}
+
+// UseLocation lets local date-times and local dates decode
+// into time.Time, placing them in loc. Without a location
+// only offset date-times decode into time.Time.
+func (d *Decoder) UseLocation(loc *time.Location) {
+	d.location = loc
+}
+
+// DisallowUnknownFields makes Decode fail on keys that
+// match no field of the target struct and are not caught
+// by a `toml:",remain"` field.
+func (d *Decoder) DisallowUnknownFields() {
+	d.strict = true
+}
+
+// Decode reads the whole TOML document from its input and
+// stores it in the value pointed to by v.
+//
+// Tables decode into structs and maps, arrays into slices
+// and arrays. Struct fields are matched by their `toml` tag,
+// the `json` tag or their name. Untagged fields also match
+// snake_case keys, so server_port fills ServerPort. Fields
+// without a key in the document are left untouched, unless
+// they hold their zero value and have a `default:"..."` tag.
+// Tables merge into the structs and maps they decode into.
+// Arrays replace slices, or are appended to them with the
+// `toml:",append"` option.
+//
+// Offset date-times decode into time.Time, local date-times,
+// dates and times into LocalDateTime, LocalDate and LocalTime.
+//
+// Types implementing encoding.TextUnmarshaler decode
+// from TOML strings.
+//
+// Types with a DecodeTOML method written by cmd/tomlgen decode
+// themselves from the events of the parser, unless hooks are
+// registered.
+//
+// Keys without a matching field are skipped unless the struct
+// has a map field tagged `toml:",remain"`, which collects them.
+// The returned MetaData lists the keys of the document and
+// which of them were not decoded.
+func (d *Decoder) Decode(v interface{}) (MetaData, error) {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return MetaData{}, fmt.Errorf(`toml: Decode needs a non-nil pointer, got %T`, v)
+	}
+
+	b := newBuilder()
+	if holdsRaw(rv.Type(), make(map[reflect.Type]bool)) {
+		b.src = &source{}
+	}
+	d.src = b.src
+
+	if u, ok := v.(EventUnmarshaler); ok && len(d.hooks) == 0 {
+		return d.decodeEvents(u, b)
+	}
+
+	err := b.read(d.reader, func() error { return nil })
+	if err != nil {
+		return MetaData{}, err
+	}
+
+	root := b.root
+	err = d.decodeValue(root, rv.Elem(), nil)
+	if err != nil {
+		return MetaData{}, err
+	}
+	return newMetaData(root), nil
+}
+
+// decodeValue decodes n into rv and reports all failed
+// validations at once.
+func (d *Decoder) decodeValue(n *node, rv reflect.Value, path keyPath) error {
+
+	d.violations = nil
+
+	err := d.decode(n, rv, path)
+	if err != nil {
+		return err
+	}
+
+	if len(d.violations) > 0 {
+		return &ValidationError{Errors: d.violations}
+	}
+	return nil
+}
+
+func (d *Decoder) decode(n *node, rv reflect.Value, path keyPath) error {
+
+	n.decoded = true
+
+	if rv.Type() == rawValueType {
+		return d.decodeRaw(n, rv, path)
+	}
+
+	if fn, ok := d.hook(n, rv.Type()); ok {
+		return d.decodeHook(n, rv, fn, path)
+	}
+
+	if u, ok := unmarshaler(rv); ok {
+		n.markDecoded()
+		err := u.UnmarshalTOML(n.interfaceValue())
+		if err != nil {
+			return wrapError(path, n, err)
+		}
+		return nil
+	}
+
+	if u, ok := textUnmarshaler(rv); ok && n.typ == StringType {
+		err := u.UnmarshalText([]byte(n.value.(string)))
+		if err != nil {
+			return wrapError(path, n, err)
+		}
+		return nil
+	}
+
+	switch rv.Type() {
+	case timeType, localDateType, localTimeType, localDateTimeType:
+		return d.decodeDateTime(n, rv, path)
+	case orderedMapType:
+		return d.decodeOrderedMap(n, rv, path)
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.decode(n, rv.Elem(), path)
+
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return mismatch(n, rv, path)
+		}
+		n.markDecoded()
+
+		// tables merge into the map already stored
+		if m, ok := rv.Interface().(map[string]interface{}); ok && m != nil && n.typ.isTable() {
+			return d.decodeMap(n, reflect.ValueOf(m), path)
+		}
+
+		rv.Set(reflect.ValueOf(n.interfaceValue()))
+		return nil
+
+	case reflect.Struct:
+		return d.decodeStruct(n, rv, path)
+
+	case reflect.Map:
+		return d.decodeMap(n, rv, path)
+
+	case reflect.Slice:
+		return d.decodeSlice(n, rv, path)
+
+	case reflect.Array:
+		return d.decodeArray(n, rv, path)
+	}
+
+	return d.decodeScalar(n, rv, path)
+}
+
+func (d *Decoder) decodeStruct(n *node, rv reflect.Value, path keyPath) error {
+
+	if !n.typ.isTable() {
+		return mismatch(n, rv, path)
+	}
+
+	fs := cachedFields(rv.Type())
+	remain, hasRemain := fs.remain()
+	present := make(map[string]*node, len(n.keys))
+
+	for _, key := range n.keys {
+
+		f, ok := fs.byKey(key)
+		if ok {
+			present[f.name] = n.fields[key]
+
+			err := d.decodeField(n.fields[key], rv.FieldByIndex(f.index), f, path.key(key))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if hasRemain {
+			err := d.decodeRemain(key, n.fields[key], rv.FieldByIndex(remain.index), path)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if d.strict {
+			return decodeError(path.key(key), n.fields[key], `unknown field %q`, key)
+		}
+	}
+	err := d.applyDefaults(rv, fs, present, path)
+	if err != nil {
+		return err
+	}
+
+	d.validateStruct(rv, fs, present, n, path)
+	return nil
+}
+
+func (d *Decoder) decodeRemain(key string, n *node, rv reflect.Value, path keyPath) error {
+
+	t := rv.Type()
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return decodeError(path, n, `remain field needs a map with string keys, got %v`, t)
+	}
+
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(t))
+	}
+
+	elem := reflect.New(t.Elem()).Elem()
+	err := d.decode(n, elem, path.key(key))
+	if err != nil {
+		return err
+	}
+
+	rv.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+	return nil
+}
+
+func (d *Decoder) decodeMap(n *node, rv reflect.Value, path keyPath) error {
+
+	if !n.typ.isTable() {
+		return mismatch(n, rv, path)
+	}
+
+	t := rv.Type()
+	if t.Key().Kind() != reflect.String {
+		return decodeError(path, n, `cannot decode into map with %v keys`, t.Key())
+	}
+
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(t, len(n.keys)))
+	}
+
+	for _, key := range n.keys {
+
+		// values already stored are merged with the document
+		elem := reflect.New(t.Elem()).Elem()
+		if old := rv.MapIndex(reflect.ValueOf(key).Convert(t.Key())); old.IsValid() {
+			elem.Set(old)
+		}
+
+		err := d.decode(n.fields[key], elem, path.key(key))
+		if err != nil {
+			return err
+		}
+
+		rv.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+	}
+	return nil
+}
+
+func (d *Decoder) decodeSlice(n *node, rv reflect.Value, path keyPath) error {
+
+	if !n.typ.isArray() {
+		return mismatch(n, rv, path)
+	}
+
+	slice := reflect.MakeSlice(rv.Type(), len(n.items), len(n.items))
+	for i, item := range n.items {
+		err := d.decode(item, slice.Index(i), path.index(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	rv.Set(slice)
+	return nil
+}
+
+func (d *Decoder) decodeArray(n *node, rv reflect.Value, path keyPath) error {
+
+	if !n.typ.isArray() {
+		return mismatch(n, rv, path)
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+
+		if i >= len(n.items) {
+			rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+			continue
+		}
+
+		err := d.decode(n.items[i], rv.Index(i), path.index(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) decodeDateTime(n *node, rv reflect.Value, path keyPath) error {
+
+	if rv.Type() == timeType && d.location != nil {
+		switch v := n.value.(type) {
+		case LocalDateTime:
+			rv.Set(reflect.ValueOf(v.In(d.location)))
+			return nil
+		case LocalDate:
+			rv.Set(reflect.ValueOf(v.In(d.location)))
+			return nil
+		}
+	}
+
+	v := reflect.ValueOf(n.value)
+	if !v.IsValid() || v.Type() != rv.Type() {
+		return mismatch(n, rv, path)
+	}
+
+	rv.Set(v)
+	return nil
+}
+
+func (d *Decoder) decodeScalar(n *node, rv reflect.Value, path keyPath) error {
+
+	switch rv.Kind() {
+	case reflect.String:
+		switch n.typ {
+		case StringType, DateTimeType, LocalDateTimeType, LocalDateType, LocalTimeType:
+			rv.SetString(formatDateTime(n.value))
+			return nil
+		}
+
+	case reflect.Bool:
+		if n.typ == BoolType {
+			rv.SetBool(n.value.(bool))
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n.typ == IntegerType {
+			i := n.value.(int64)
+			if rv.OverflowInt(i) {
+				return decodeError(path, n, `integer %v overflows %v`, i, rv.Type())
+			}
+			rv.SetInt(i)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n.typ == IntegerType {
+			i := n.value.(int64)
+			if i < 0 || rv.OverflowUint(uint64(i)) {
+				return decodeError(path, n, `integer %v overflows %v`, i, rv.Type())
+			}
+			rv.SetUint(uint64(i))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch n.typ {
+		case FloatType:
+			f := n.value.(float64)
+			if rv.OverflowFloat(f) {
+				return decodeError(path, n, `float %v overflows %v`, f, rv.Type())
+			}
+			rv.SetFloat(f)
+			return nil
+		case IntegerType:
+			rv.SetFloat(float64(n.value.(int64)))
+			return nil
+		}
+	}
+
+	return mismatch(n, rv, path)
+}
+
+func unmarshaler(rv reflect.Value) (Unmarshaler, bool) {
+
+	if !rv.CanAddr() {
+		return nil, false
+	}
+
+	u, ok := rv.Addr().Interface().(Unmarshaler)
+	return u, ok
+}
+
+func textUnmarshaler(rv reflect.Value) (encoding.TextUnmarshaler, bool) {
+
+	if !rv.CanAddr() {
+		return nil, false
+	}
+
+	u, ok := rv.Addr().Interface().(encoding.TextUnmarshaler)
+	return u, ok
+}
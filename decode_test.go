@@ -0,0 +1,432 @@
+package toml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal_struct(t *testing.T) {
+
+	type Server struct {
+		Host       string `toml:"host"`
+		ServerPort int
+		Tags       []string `json:"labels"`
+	}
+
+	type Config struct {
+		Title   string
+		Owner   struct{ Name string }
+		Servers []Server `toml:"servers"`
+		Ratio   float64
+		Enabled *bool
+		Skipped string `toml:"-"`
+	}
+
+	doc := `
+title = "example"
+ratio = 2
+enabled = true
+skipped = "no"
+
+[owner]
+name = "Tom"
+
+[[servers]]
+host = "alpha"
+server_port = 8080
+labels = ["a", 'b']
+
+[[servers]]
+host = "beta"
+server-port = 8081
+`
+
+	var c Config
+	err := Unmarshal([]byte(doc), &c)
+	require.NoError(t, err)
+
+	assert.Equal(t, `example`, c.Title)
+	assert.Equal(t, `Tom`, c.Owner.Name)
+	assert.Equal(t, 2.0, c.Ratio)
+	require.NotNil(t, c.Enabled)
+	assert.True(t, *c.Enabled)
+	assert.Equal(t, ``, c.Skipped)
+	assert.Equal(t, []Server{
+		{Host: `alpha`, ServerPort: 8080, Tags: []string{`a`, `b`}},
+		{Host: `beta`, ServerPort: 8081},
+	}, c.Servers)
+}
+
+func TestUnmarshal_keepsAbsentFields(t *testing.T) {
+
+	c := struct {
+		A string
+		B string
+	}{A: `a`, B: `b`}
+
+	err := Unmarshal([]byte(`b = "x"`), &c)
+	require.NoError(t, err)
+	assert.Equal(t, `a`, c.A)
+	assert.Equal(t, `x`, c.B)
+}
+
+func TestUnmarshal_interface(t *testing.T) {
+
+	doc := `
+int = 9007199254740993
+hex = 0xff
+flt = 1.5e3
+inf = -inf
+str = """multi
+line"""
+arr = [1, "two", {three = 3}]
+a.b.c = true
+`
+
+	var m map[string]interface{}
+	err := Unmarshal([]byte(doc), &m)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(9007199254740993), m[`int`])
+	assert.Equal(t, int64(255), m[`hex`])
+	assert.Equal(t, 1500.0, m[`flt`])
+	assert.True(t, math.IsInf(m[`inf`].(float64), -1))
+	assert.Equal(t, "multi\nline", m[`str`])
+	assert.Equal(t, []interface{}{int64(1), `two`, map[string]interface{}{`three`: int64(3)}}, m[`arr`])
+	assert.Equal(t, map[string]interface{}{`b`: map[string]interface{}{`c`: true}}, m[`a`])
+}
+
+func TestUnmarshal_dateTime(t *testing.T) {
+
+	doc := `
+odt = 1979-05-27T07:32:00.999999-07:00
+space = 1979-05-27 07:32:00Z
+ldt = 1979-05-27T07:32:00
+ld = 1979-05-27
+lt = 00:32:00.5
+text = 1979-05-27T07:32:00Z
+`
+
+	var st struct {
+		ODT   time.Time
+		Space time.Time
+		LDT   LocalDateTime
+		LD    LocalDate
+		LT    LocalTime
+		Text  string
+	}
+
+	err := Unmarshal([]byte(doc), &st)
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(1979, 5, 27, 7, 32, 0, 999999000, time.FixedZone(``, -7*3600)).Unix(), st.ODT.Unix())
+	assert.Equal(t, 999999000, st.ODT.Nanosecond())
+	assert.Equal(t, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC), st.Space.UTC())
+	assert.Equal(t, LocalDateTime{Date: LocalDate{1979, 5, 27}, Time: LocalTime{7, 32, 0, 0}}, st.LDT)
+	assert.Equal(t, LocalDate{1979, 5, 27}, st.LD)
+	assert.Equal(t, LocalTime{0, 32, 0, 500000000}, st.LT)
+	assert.Equal(t, `1979-05-27T07:32:00Z`, st.Text)
+
+	var local struct {
+		LDT time.Time
+		LD  time.Time
+	}
+
+	err = Unmarshal([]byte(doc), &local)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `toml: ldt (line 4, col 7): expected datetime, found datetime-local 1979-05-27T07:32:00`)
+
+	loc := time.FixedZone(`test`, 3600)
+	dec := NewDecoder(strings.NewReader(doc))
+	dec.UseLocation(loc)
+	_, err = dec.Decode(&local)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(1979, 5, 27, 7, 32, 0, 0, loc), local.LDT)
+	assert.Equal(t, time.Date(1979, 5, 27, 0, 0, 0, 0, loc), local.LD)
+
+	var m map[string]interface{}
+	err = Unmarshal([]byte(doc), &m)
+	require.NoError(t, err)
+	assert.IsType(t, time.Time{}, m[`odt`])
+	assert.IsType(t, LocalDateTime{}, m[`ldt`])
+	assert.IsType(t, LocalDate{}, m[`ld`])
+	assert.IsType(t, LocalTime{}, m[`lt`])
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+
+	tests := []struct {
+		doc string
+		v   interface{}
+		err string
+	}{
+		{
+			doc: `a = "x"`,
+			v:   &struct{ A int }{},
+			err: `toml: a (line 1, col 5): expected integer, found string "x"`,
+		},
+		{
+			doc: `a = 300`,
+			v:   &struct{ A int8 }{},
+			err: `toml: a (line 1, col 5): integer 300 overflows int8`,
+		},
+		{
+			doc: `a = -1`,
+			v:   &struct{ A uint }{},
+			err: `toml: a (line 1, col 5): integer -1 overflows uint`,
+		},
+		{
+			doc: `a = -1e300`,
+			v:   &struct{ A float32 }{},
+			err: `toml: a (line 1, col 5): float -1e+300 overflows float32`,
+		},
+		{
+			doc: `[a]
+			b = [1, "x"]`,
+			v:   &struct{ A struct{ B []int } }{},
+			err: `toml: a.b[1] (line 2, col 12): expected integer, found string "x"`,
+		},
+		{
+			doc: `a = 1`,
+			v:   struct{}{},
+			err: `toml: Decode needs a non-nil pointer`,
+		},
+		{
+			doc: `a = """x`,
+			v:   &struct{}{},
+			err: `invalid EOF`,
+		},
+		{
+			doc: `a = 99999999999999999999`,
+			v:   &struct{}{},
+			err: `out of range`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		t.Log(`doc`, ts.doc)
+
+		err := Unmarshal([]byte(ts.doc), ts.v)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ts.err)
+	}
+
+	// infinity is no overflow
+	var f struct{ A float32 }
+	require.NoError(t, Unmarshal([]byte(`a = -inf`), &f))
+	assert.True(t, math.IsInf(float64(f.A), -1))
+}
+
+func TestUnmarshal_specs(t *testing.T) {
+
+	err := filepath.Walk(`spec-tests/tests/valid`, func(path string, info os.FileInfo, e error) error {
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), `.toml`) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		parsedJSON, err := io.ReadAll(New(bytes.NewReader(data)))
+		if err != nil {
+			return nil
+		}
+
+		expected := map[string]interface{}{}
+		err = json.Unmarshal(parsedJSON, &expected)
+		require.NoError(t, err)
+
+		decoded := map[string]interface{}{}
+		err = Unmarshal(data, &decoded)
+		require.NoError(t, err, path)
+
+		assert.Equal(t, normalizeJSON(expected), normalizeJSON(decoded), path)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// normalizeJSON maps decoded values onto what the JSON
+// stream can express.
+func normalizeJSON(v interface{}) interface{} {
+
+	switch o := v.(type) {
+	case map[string]interface{}:
+		for k, item := range o {
+			o[k] = normalizeJSON(item)
+		}
+	case []interface{}:
+		for i, item := range o {
+			o[i] = normalizeJSON(item)
+		}
+	case int64:
+		return float64(o)
+	case float64:
+		if math.IsNaN(o) {
+			return `nan`
+		}
+		if math.IsInf(o, 1) {
+			return `inf`
+		}
+		if math.IsInf(o, -1) {
+			return `-inf`
+		}
+	case time.Time, LocalDateTime, LocalDate, LocalTime:
+		return formatDateTime(o)
+	case string:
+		switch o {
+		case `+inf`:
+			return `inf`
+		case `+nan`, `-nan`:
+			return `nan`
+		}
+		if _, dt, err := parseDateTime(o); err == nil {
+			return formatDateTime(dt)
+		}
+	}
+	return v
+}
+
+type byteSize int64
+
+func (b *byteSize) UnmarshalTOML(value interface{}) error {
+
+	switch v := value.(type) {
+	case int64:
+		*b = byteSize(v)
+		return nil
+	case string:
+		units := map[string]int64{`KiB`: 1 << 10, `MiB`: 1 << 20}
+		for suffix, unit := range units {
+			if strings.HasSuffix(v, suffix) {
+				var n int64
+				_, err := fmt.Sscan(strings.TrimSuffix(v, suffix), &n)
+				*b = byteSize(n * unit)
+				return err
+			}
+		}
+	}
+	return fmt.Errorf(`invalid size %v`, value)
+}
+
+type stringSet map[string]bool
+
+func (s *stringSet) UnmarshalTOML(value interface{}) error {
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf(`expected array`)
+	}
+
+	*s = stringSet{}
+	for _, item := range items {
+		(*s)[fmt.Sprint(item)] = true
+	}
+	return nil
+}
+
+type endpoint struct {
+	raw map[string]interface{}
+}
+
+func (e *endpoint) UnmarshalTOML(value interface{}) error {
+	e.raw = value.(map[string]interface{})
+	return nil
+}
+
+func TestUnmarshal_unmarshaler(t *testing.T) {
+
+	doc := `
+cache = "10MiB"
+limit = 512
+tags = ["a", "b", "a"]
+
+[endpoint]
+url = "http://localhost"
+retries = 3
+`
+
+	var st struct {
+		Cache    byteSize
+		Limit    *byteSize
+		Tags     stringSet
+		Endpoint endpoint
+	}
+
+	md, err := NewDecoder(strings.NewReader(doc)).Decode(&st)
+	require.NoError(t, err)
+
+	assert.Equal(t, byteSize(10<<20), st.Cache)
+	require.NotNil(t, st.Limit)
+	assert.Equal(t, byteSize(512), *st.Limit)
+	assert.Equal(t, stringSet{`a`: true, `b`: true}, st.Tags)
+	assert.Equal(t, map[string]interface{}{`url`: `http://localhost`, `retries`: int64(3)}, st.Endpoint.raw)
+	assert.Empty(t, md.Undecoded())
+
+	err = Unmarshal([]byte(`cache = true`), &st)
+	require.Error(t, err)
+	assert.Equal(t, `toml: cache (line 1, col 9): invalid size true`, err.Error())
+}
+
+type logLevel int
+
+func (l *logLevel) UnmarshalText(text []byte) error {
+
+	for i, name := range []string{`debug`, `info`, `warn`} {
+		if string(text) == name {
+			*l = logLevel(i)
+			return nil
+		}
+	}
+	return fmt.Errorf(`unknown log level %q`, text)
+}
+
+func TestUnmarshal_textUnmarshaler(t *testing.T) {
+
+	doc := `
+level = "warn"
+ip = "10.0.0.1"
+day = "2021-03-04"
+
+[[servers]]
+level = "info"
+[[servers]]
+level = "trace"
+`
+
+	var st struct {
+		Level   logLevel
+		IP      net.IP
+		Day     LocalDate
+		Servers []struct {
+			Level logLevel
+		}
+	}
+
+	err := Unmarshal([]byte(doc), &st)
+	require.Error(t, err)
+	assert.Equal(t, `toml: servers[1].level (line 9, col 9): unknown log level "trace"`, err.Error())
+
+	assert.Equal(t, logLevel(2), st.Level)
+	assert.Equal(t, net.ParseIP(`10.0.0.1`), st.IP)
+	assert.Equal(t, LocalDate{2021, 3, 4}, st.Day)
+
+	var level struct{ Level logLevel }
+	err = Unmarshal([]byte(`level = true`), &level)
+	require.Error(t, err)
+	assert.Equal(t, `toml: level (line 1, col 9): expected integer, found bool true`, err.Error())
+}
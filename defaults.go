@@ -0,0 +1,93 @@
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// decodeField decodes n into the struct field rv.
+func (d *Decoder) decodeField(n *node, rv reflect.Value, f field, path keyPath) error {
+
+	if !f.appendSlice || rv.Kind() != reflect.Slice {
+		return d.decode(n, rv, path)
+	}
+
+	items := reflect.New(rv.Type()).Elem()
+	err := d.decode(n, items, path)
+	if err != nil {
+		return err
+	}
+
+	rv.Set(reflect.AppendSlice(rv, items))
+	return nil
+}
+
+// applyDefaults sets the fields of the struct rv whose keys
+// are not present in the document to their default, unless
+// they hold a value already. Nested structs get their
+// defaults as well.
+func (d *Decoder) applyDefaults(rv reflect.Value, fs fields, present map[string]*node, path keyPath) error {
+
+	for _, f := range fs {
+
+		if f.remain || present[f.name] != nil {
+			continue
+		}
+
+		fv := rv.FieldByIndex(f.index)
+
+		if f.hasDefault {
+			if !fv.IsZero() {
+				continue
+			}
+
+			err := d.decodeDefault(f.defaultValue, fv, path.key(f.name))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Struct {
+			err := d.applyDefaults(fv, cachedFields(fv.Type()), nil, path.key(f.name))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) decodeDefault(value string, rv reflect.Value, path keyPath) error {
+
+	err := d.decode(defaultNode(value, rv.Type()), rv, path)
+	if err != nil {
+		var decErr *DecodeError
+		if errors.As(err, &decErr) {
+			err = decErr.Err
+		}
+		return fmt.Errorf(`toml: %v: invalid default %q: %w`, path, value, err)
+	}
+	return nil
+}
+
+// defaultNode returns the value of a default tag. Defaults are
+// written as TOML values, like 8080, [1, 2] or 1979-05-27.
+// Anything else is taken as string, so string fields need
+// no quotes.
+func defaultNode(value string, t reflect.Type) *node {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.String {
+		root, err := parse(strings.NewReader(`v = ` + value))
+		if err == nil && len(root.keys) == 1 {
+			return root.fields[`v`]
+		}
+	}
+	return &node{typ: StringType, value: value}
+}
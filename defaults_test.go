@@ -0,0 +1,118 @@
+package toml
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode_defaults(t *testing.T) {
+
+	type tls struct {
+		Enabled bool   `default:"true"`
+		Cert    string `default:"cert.pem"`
+	}
+
+	type config struct {
+		Host    string        `default:"localhost"`
+		Port    int           `default:"8080"`
+		Ratio   *float64      `default:"0.5"`
+		Tags    []string      `default:"[\"a\", \"b\"]"`
+		Version string        `default:"1.0"`
+		Timeout time.Duration `default:"30s"`
+		Level   logLevel      `default:"warn"`
+		Since   LocalDate     `default:"1979-05-27"`
+		TLS     tls
+	}
+
+	doc := `
+port = 9090
+
+[tls]
+cert = "other.pem"
+`
+
+	dec := NewDecoder(strings.NewReader(doc))
+	dec.UseHooks(DurationHook)
+
+	var c config
+	_, err := dec.Decode(&c)
+	require.NoError(t, err)
+
+	require.NotNil(t, c.Ratio)
+	assert.Equal(t, `localhost`, c.Host)
+	assert.Equal(t, 9090, c.Port)
+	assert.Equal(t, 0.5, *c.Ratio)
+	assert.Equal(t, []string{`a`, `b`}, c.Tags)
+	assert.Equal(t, `1.0`, c.Version)
+	assert.Equal(t, 30*time.Second, c.Timeout)
+	assert.Equal(t, logLevel(2), c.Level)
+	assert.Equal(t, LocalDate{Year: 1979, Month: 5, Day: 27}, c.Since)
+	assert.Equal(t, tls{Enabled: true, Cert: `other.pem`}, c.TLS)
+
+	var bad struct {
+		Port int `default:"http"`
+	}
+	err = Unmarshal([]byte(``), &bad)
+	assert.EqualError(t, err, `toml: Port: invalid default "http": expected integer, found string "http"`)
+}
+
+func TestDecode_merge(t *testing.T) {
+
+	type server struct {
+		Host string
+		Port int `default:"80"`
+	}
+
+	type config struct {
+		Name    string
+		Server  server
+		Backup  *server
+		Plugins map[string]server
+		Extra   map[string]interface{}
+		Hosts   []string
+		Extends []string `toml:",append"`
+	}
+
+	c := config{
+		Name:    `base`,
+		Server:  server{Host: `localhost`, Port: 8080},
+		Backup:  &server{Host: `backup`, Port: 8081},
+		Plugins: map[string]server{`cache`: {Host: `cache`, Port: 6379}},
+		Extra:   map[string]interface{}{`a`: map[string]interface{}{`x`: int64(1)}},
+		Hosts:   []string{`a`, `b`},
+		Extends: []string{`a`, `b`},
+	}
+
+	doc := `
+hosts = ["c"]
+extends = ["c"]
+
+[server]
+host = "example.com"
+
+[backup]
+port = 9091
+
+[plugins.cache]
+port = 6380
+
+[extra.a]
+y = 2
+`
+
+	require.NoError(t, Unmarshal([]byte(doc), &c))
+
+	assert.Equal(t, config{
+		Name:    `base`,
+		Server:  server{Host: `example.com`, Port: 8080},
+		Backup:  &server{Host: `backup`, Port: 9091},
+		Plugins: map[string]server{`cache`: {Host: `cache`, Port: 6380}},
+		Extra:   map[string]interface{}{`a`: map[string]interface{}{`x`: int64(1), `y`: int64(2)}},
+		Hosts:   []string{`c`},
+		Extends: []string{`a`, `b`, `c`},
+	}, c)
+}
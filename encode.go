@@ -0,0 +1,830 @@
+package toml
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Marshal returns the TOML document for v, which has to
+// be a struct or a map with string keys.
+func Marshal(v interface{}) ([]byte, error) {
+
+	var buf bytes.Buffer
+	err := NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Marshaler is implemented by types that write themselves
+// as TOML value. MarshalTOML returns the value as it appears
+// after the equals sign, like "10MiB" or [1, 2]. The encoder
+// checks that the fragment is a single valid TOML value, also
+// inside arrays and inline tables, so it must not end in a
+// comment.
+type Marshaler interface {
+	MarshalTOML() ([]byte, error)
+}
+
+// An Encoder writes TOML documents to an output stream.
+type Encoder struct {
+	writer      io.Writer
+	inlineDepth int
+	dotted      bool
+	indent      string
+	arrayWidth  int
+	strings     StringStyle
+	policy      Policy
+
+	// sample is set by GenerateSample
+	sample    bool
+	docs      map[string]string
+	commented bool
+}
+
+// StringStyle selects how the encoder writes strings.
+// Keys are always written as bare keys or basic strings.
+type StringStyle int
+
+const (
+	// BasicStrings writes all strings as "basic" strings
+	// with escapes.
+	BasicStrings StringStyle = 0
+
+	// LiteralStrings writes strings as 'literal' strings
+	// if they need no escapes.
+	LiteralStrings StringStyle = 1
+
+	// MultilineStrings writes strings containing newlines
+	// as """multi-line""" strings, or as '''raw''' strings
+	// together with LiteralStrings.
+	MultilineStrings StringStyle = 2
+)
+
+// NewEncoder returns a new encoder that writes to writer.
+func NewEncoder(writer io.Writer) *Encoder {
+	return &Encoder{writer: writer}
+}
+
+// SetInlineDepth makes the encoder write tables nested depth
+// levels or deeper as inline tables, and arrays of them as
+// arrays of inline tables. Tables of the document itself have
+// depth 1. With depth 0, the default, no table is inlined.
+func (e *Encoder) SetInlineDepth(depth int) {
+	e.inlineDepth = depth
+}
+
+// SetDottedKeys makes the encoder write nested tables as
+// dotted keys of their parent table instead of [table]
+// sections. Arrays of tables keep their [[array]] sections.
+func (e *Encoder) SetDottedKeys(dotted bool) {
+	e.dotted = dotted
+}
+
+// SetIndent indents the keys of tables and the sections of
+// subtables by indent per nesting level. Elements of wrapped
+// arrays are indented by indent as well.
+func (e *Encoder) SetIndent(indent string) {
+	e.indent = indent
+}
+
+// SetArrayWidth makes the encoder write arrays with one element
+// per line if they would make their line longer than width.
+// With width 0, the default, arrays stay on one line.
+func (e *Encoder) SetArrayWidth(width int) {
+	e.arrayWidth = width
+}
+
+// SetStringStyle selects how strings are written, BasicStrings
+// by default. Styles can be combined with |.
+func (e *Encoder) SetStringStyle(style StringStyle) {
+	e.strings = style
+}
+
+// SetPolicy decides how values TOML has no syntax for are
+// written, like nil or arrays mixing tables and other values.
+// The zero Policy is used by default.
+func (e *Encoder) SetPolicy(policy Policy) {
+	e.policy = policy
+}
+
+// Encode writes the TOML document for v.
+//
+// Structs and maps become tables. Their scalars and arrays
+// are written first, followed by nested tables as [table]
+// sections and slices of tables as [[array]] sections.
+// Inside arrays tables are written inline. Struct fields use
+// the same names and tags as Decode. Map keys are sorted.
+// Nil pointers and interfaces are left out unless the Policy
+// says otherwise.
+//
+// time.Time is written as offset date-time, LocalDateTime,
+// LocalDate and LocalTime as their local counterparts. Types
+// implementing Marshaler write themselves, types implementing
+// encoding.TextMarshaler are written as strings.
+func (e *Encoder) Encode(v interface{}) error {
+
+	rv := indirect(reflect.ValueOf(v))
+	if !isTable(rv) {
+		return fmt.Errorf(`toml: cannot encode %T as document, need a struct or map`, v)
+	}
+
+	var buf bytes.Buffer
+	err := e.encodeTable(&buf, nil, rv, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.writer.Write(buf.Bytes())
+	return err
+}
+
+type entry struct {
+	key   string
+	value reflect.Value
+	path  keyPath
+
+	// comment is written above the key, commented
+	// entries are written as comment.
+	comment   string
+	commented bool
+}
+
+// section is a table or array of tables written
+// under its own header.
+type section struct {
+	key       Key
+	value     reflect.Value
+	path      keyPath
+	comment   string
+	commented bool
+	array     bool
+}
+
+func (e *Encoder) encodeTable(buf *bytes.Buffer, key Key, rv reflect.Value, path keyPath) error {
+
+	// tables are written before arrays of tables, except
+	// for ordered maps keeping both in key order
+	var tables, arrays []section
+	headers := &arrays
+	if rv.Type() == orderedMapType {
+		headers = &tables
+	}
+
+	err := e.encodeKeys(buf, key, nil, rv, path, &tables, headers)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range append(tables, arrays...) {
+
+		if !s.array {
+			err := e.encodeSection(buf, s, `[`+s.key.String()+`]`, s.value, s.path)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for i := 0; i < s.value.Len(); i++ {
+
+			err := e.encodeSection(buf, s, `[[`+s.key.String()+`]]`, indirect(s.value.Index(i)), s.path.index(i))
+			if err != nil {
+				return err
+			}
+
+			// the comment goes above the first element only
+			s.comment = ``
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) encodeSection(buf *bytes.Buffer, s section, header string, rv reflect.Value, path keyPath) error {
+
+	level := len(s.key) - 1
+
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	e.writeComment(buf, s.comment, level)
+
+	return e.writeCommented(buf, s.commented, func(buf *bytes.Buffer) error {
+		e.writeIndent(buf, level)
+		buf.WriteString(header + "\n")
+		return e.encodeTable(buf, s.key, rv, path)
+	})
+}
+
+// encodeKeys writes the keys of the table rv at key. In dotted
+// mode subtables are written here as well, with their keys
+// below prefix. Tables and arrays of tables that need a header
+// are collected.
+func (e *Encoder) encodeKeys(buf *bytes.Buffer, key Key, prefix Key, rv reflect.Value, path keyPath, tables *[]section, arrays *[]section) error {
+
+	entries, err := e.tableEntries(rv, path)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 && len(prefix) > 0 {
+		e.writeIndent(buf, len(key))
+		buf.WriteString(prefix.String() + " = {}\n")
+		return nil
+	}
+
+	inline := e.inlineDepth > 0 && len(key)+len(prefix)+1 >= e.inlineDepth
+
+	for _, en := range entries {
+
+		sub := append(append(Key(nil), prefix...), en.key)
+
+		switch {
+		case isTable(en.value) && !inline && e.dotted:
+			err := e.encodeKeys(buf, key, sub, en.value, en.path, tables, arrays)
+			if err != nil {
+				return err
+			}
+			continue
+
+		case isTable(en.value) && !inline:
+			*tables = append(*tables, en.section(key, sub))
+			continue
+
+		case isArrayOfTables(en.value) && !inline:
+			s := en.section(key, sub)
+			s.array = true
+			*arrays = append(*arrays, s)
+			continue
+		}
+
+		e.writeComment(buf, en.comment, len(key))
+
+		err := e.writeCommented(buf, en.commented, func(buf *bytes.Buffer) error {
+			e.writeIndent(buf, len(key))
+			buf.WriteString(sub.String() + ` = `)
+
+			err := e.encodeValue(buf, en.value, en.path, len(key), false)
+			buf.WriteByte('\n')
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (en entry) section(key Key, sub Key) section {
+	return section{
+		key:       append(append(Key(nil), key...), sub...),
+		value:     en.value,
+		path:      en.path,
+		comment:   en.comment,
+		commented: en.commented,
+	}
+}
+
+func (e *Encoder) writeIndent(buf *bytes.Buffer, level int) {
+	for i := 0; i < level; i++ {
+		buf.WriteString(e.indent)
+	}
+}
+
+// encodeValue writes rv as value on a line indented by level.
+// Inline values, which are part of inline tables, stay on
+// one line.
+func (e *Encoder) encodeValue(buf *bytes.Buffer, rv reflect.Value, path keyPath, level int, inline bool) error {
+
+	rv = indirect(rv)
+	if !rv.IsValid() {
+		return encodeError(path, `cannot encode nil`)
+	}
+
+	if m, ok := marshaler(rv); ok {
+		return encodeMarshaler(buf, m, path)
+	}
+
+	switch rv.Type() {
+	case timeType:
+		buf.WriteString(rv.Interface().(time.Time).Format(time.RFC3339Nano))
+		return nil
+	case localDateType, localTimeType, localDateTimeType:
+		buf.WriteString(rv.Interface().(fmt.Stringer).String())
+		return nil
+	}
+
+	if m, ok := textMarshaler(rv); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return encodeError(path, `%v`, err)
+		}
+		buf.WriteString(e.formatString(string(text), inline))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		buf.WriteString(e.formatString(rv.String(), inline))
+
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(rv.Bool()))
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(rv.Int(), 10))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return encodeError(path, `integer %v out of range`, rv.Uint())
+		}
+		buf.WriteString(strconv.FormatUint(rv.Uint(), 10))
+
+	case reflect.Float32, reflect.Float64:
+		buf.WriteString(formatFloat(rv.Float(), rv.Type().Bits()))
+
+	case reflect.Slice, reflect.Array:
+		return e.encodeArray(buf, rv, path, level, inline)
+
+	case reflect.Struct, reflect.Map:
+		return e.encodeInlineTable(buf, rv, path)
+
+	default:
+		return encodeError(path, `cannot encode %v`, rv.Type())
+	}
+	return nil
+}
+
+func (e *Encoder) encodeArray(buf *bytes.Buffer, rv reflect.Value, path keyPath, level int, inline bool) error {
+
+	items, err := e.arrayItems(rv, path)
+	if err != nil {
+		return err
+	}
+
+	var line bytes.Buffer
+	line.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			line.WriteString(`, `)
+		}
+		err := e.encodeValue(&line, item.value, item.path, level, true)
+		if err != nil {
+			return err
+		}
+	}
+	line.WriteByte(']')
+
+	column := buf.Len() - bytes.LastIndexByte(buf.Bytes(), '\n') - 1
+	if inline || e.arrayWidth == 0 || len(items) == 0 || column+line.Len() <= e.arrayWidth {
+		buf.Write(line.Bytes())
+		return nil
+	}
+
+	buf.WriteString("[\n")
+	for _, item := range items {
+		e.writeIndent(buf, level+1)
+		err := e.encodeValue(buf, item.value, item.path, level+1, false)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(",\n")
+	}
+	e.writeIndent(buf, level)
+	buf.WriteByte(']')
+	return nil
+}
+
+// arrayItems returns the elements of the array rv, with nil
+// values and mixed arrays handled by the policy.
+func (e *Encoder) arrayItems(rv reflect.Value, path keyPath) ([]entry, error) {
+
+	var items []entry
+	for i := 0; i < rv.Len(); i++ {
+
+		v := indirect(rv.Index(i))
+		if !v.IsValid() {
+			nv, ok, err := e.policy.nilValue(path.index(i))
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			v = nv
+		}
+
+		if e.policy.Mixed == MixedError && len(items) > 0 && isTable(v) != isTable(items[0].value) {
+			return nil, encodeError(path.index(i), `cannot mix tables and other values`)
+		}
+
+		items = append(items, entry{value: v, path: path.index(i)})
+	}
+	return items, nil
+}
+
+func (e *Encoder) encodeInlineTable(buf *bytes.Buffer, rv reflect.Value, path keyPath) error {
+
+	entries, err := e.tableEntries(rv, path)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		buf.WriteString(`{}`)
+		return nil
+	}
+
+	buf.WriteString(`{ `)
+	for i, en := range entries {
+		if i > 0 {
+			buf.WriteString(`, `)
+		}
+
+		buf.WriteString(Key{en.key}.String() + ` = `)
+		err := e.encodeValue(buf, en.value, en.path, 0, true)
+		if err != nil {
+			return err
+		}
+	}
+	buf.WriteString(` }`)
+	return nil
+}
+
+// formatString returns s in the string style of the encoder.
+// Inline strings cannot span lines.
+func (e *Encoder) formatString(s string, inline bool) string {
+
+	literal := e.strings&LiteralStrings != 0
+	multiline := e.strings&MultilineStrings != 0 && !inline && strings.Contains(s, "\n")
+
+	switch {
+	case multiline && literal && isLiteral(s, true):
+		return "'''\n" + s + `'''`
+	case multiline:
+		return `"""` + "\n" + escapeString(s, true) + `"""`
+	case literal && isLiteral(s, false):
+		return `'` + s + `'`
+	}
+	return quoteString(s)
+}
+
+// tableEntries returns the keys of the struct or map rv with
+// their values, with nil values handled by the policy.
+func (e *Encoder) tableEntries(rv reflect.Value, path keyPath) ([]entry, error) {
+
+	var entries []entry
+
+	add := func(key string, v reflect.Value, comment string) error {
+
+		if e.sample {
+			en, ok := e.sampleEntry(key, v, comment, path)
+			if ok {
+				entries = append(entries, en)
+			}
+			return nil
+		}
+
+		v = indirect(v)
+		if !v.IsValid() {
+			nv, ok, err := e.policy.nilValue(path.key(key))
+			if !ok {
+				return err
+			}
+			v = nv
+		}
+
+		entries = append(entries, entry{key: key, value: v, path: path.key(key)})
+		return nil
+	}
+
+	if rv.Type() == orderedMapType {
+
+		m := rv.Interface().(OrderedMap)
+		for _, key := range m.keys {
+			err := add(key, reflect.ValueOf(m.values[key]), ``)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+	}
+
+	if rv.Kind() == reflect.Map {
+
+		keys := make([]string, 0, rv.Len())
+		values := make(map[string]reflect.Value, rv.Len())
+
+		for _, k := range rv.MapKeys() {
+			key, err := e.policy.mapKey(k, path)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+			values[key] = rv.MapIndex(k)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			err := add(key, values[key], ``)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+	}
+
+	for _, f := range cachedFields(rv.Type()) {
+
+		fv := rv.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyValue(fv) && !e.sample {
+			continue
+		}
+
+		if f.remain {
+			if !indirect(fv).IsValid() {
+				continue
+			}
+
+			remain, err := e.tableEntries(indirect(fv), path)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, remain...)
+			continue
+		}
+
+		if e.sample && f.hasDefault && fv.IsZero() {
+			dv, err := sampleDefault(f, fv.Type(), path)
+			if err != nil {
+				return nil, err
+			}
+			fv = dv
+		}
+
+		err := add(f.name, fv, f.comment)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// indirect follows pointers and interfaces. It returns the
+// zero Value for nil.
+func indirect(rv reflect.Value) reflect.Value {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// isTable tells whether rv is written as table.
+func isTable(rv reflect.Value) bool {
+
+	if !rv.IsValid() {
+		return false
+	}
+
+	if _, ok := marshaler(rv); ok {
+		return false
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Struct:
+		switch rv.Type() {
+		case timeType, localDateType, localTimeType, localDateTimeType:
+			return false
+		}
+		_, ok := textMarshaler(rv)
+		return !ok
+	}
+	return false
+}
+
+// isArrayOfTables tells whether rv is a non-empty slice
+// or array holding only tables.
+func isArrayOfTables(rv reflect.Value) bool {
+
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return false
+	}
+
+	if rv.Len() == 0 {
+		return false
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if !isTable(indirect(rv.Index(i))) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEmptyValue(rv reflect.Value) bool {
+
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func textMarshaler(rv reflect.Value) (encoding.TextMarshaler, bool) {
+
+	if m, ok := rv.Interface().(encoding.TextMarshaler); ok {
+		return m, true
+	}
+
+	if rv.CanAddr() {
+		m, ok := rv.Addr().Interface().(encoding.TextMarshaler)
+		return m, ok
+	}
+	return nil, false
+}
+
+func marshaler(rv reflect.Value) (Marshaler, bool) {
+
+	if m, ok := rv.Interface().(Marshaler); ok {
+		return m, true
+	}
+
+	if rv.CanAddr() {
+		m, ok := rv.Addr().Interface().(Marshaler)
+		return m, ok
+	}
+	return nil, false
+}
+
+func encodeMarshaler(buf *bytes.Buffer, m Marshaler, path keyPath) error {
+
+	fragment, err := m.MarshalTOML()
+	if err != nil {
+		return encodeError(path, `%v`, err)
+	}
+
+	fragment = bytes.TrimSpace(fragment)
+
+	err = checkFragment(fragment)
+	if err != nil {
+		return encodeError(path, `MarshalTOML returned invalid value %q: %v`, fragment, err)
+	}
+
+	buf.Write(fragment)
+	return nil
+}
+
+// fragmentContexts are the places a MarshalTOML fragment is
+// written to: the value of a key, an array item and the value
+// of an inline table key. Comments and newlines only fit the
+// first one.
+var fragmentContexts = []struct {
+	prefix string
+	suffix string
+	count  func(v *node) int
+}{
+	{prefix: `v = `, count: func(v *node) int { return 1 }},
+	{prefix: `v = [`, suffix: `]`, count: func(v *node) int { return len(v.items) }},
+	{prefix: `v = { x = `, suffix: ` }`, count: func(v *node) int { return len(v.fields) }},
+}
+
+// checkFragment checks that fragment parses as a single value
+// in every place it can be written to.
+func checkFragment(fragment []byte) error {
+
+	for _, c := range fragmentContexts {
+
+		root, err := parse(io.MultiReader(
+			strings.NewReader(c.prefix),
+			bytes.NewReader(fragment),
+			strings.NewReader(c.suffix)))
+		if err != nil {
+			return err
+		}
+
+		v, ok := root.fields[`v`]
+		if len(root.keys) != 1 || !ok || c.count(v) != 1 {
+			return fmt.Errorf(`more than one value`)
+		}
+	}
+	return nil
+}
+
+func formatFloat(f float64, bits int) string {
+
+	switch {
+	case math.IsNaN(f):
+		return `nan`
+	case math.IsInf(f, 1):
+		return `inf`
+	case math.IsInf(f, -1):
+		return `-inf`
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, `.e`) {
+		s += `.0`
+	}
+	return s
+}
+
+// quoteString returns s as TOML basic string.
+func quoteString(s string) string {
+	return `"` + escapeString(s, false) + `"`
+}
+
+// escapeString escapes s for basic strings. Multi-line
+// strings keep their newlines.
+func escapeString(s string, multiline bool) string {
+
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			if multiline {
+				b.WriteByte('\n')
+				continue
+			}
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r > 0xffff && !unicode.IsPrint(r) {
+				fmt.Fprintf(&b, `\U%08X`, r)
+				continue
+			}
+			if !unicode.IsPrint(r) {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isLiteral tells whether s can be written as literal
+// string, which has no escapes.
+func isLiteral(s string, multiline bool) bool {
+
+	if multiline && (strings.Contains(s, `'''`) || strings.HasSuffix(s, `'`)) {
+		return false
+	}
+	if !multiline && strings.Contains(s, `'`) {
+		return false
+	}
+
+	for _, r := range s {
+		if r == '\t' || multiline && r == '\n' {
+			continue
+		}
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func encodeError(path keyPath, format string, args ...interface{}) error {
+
+	msg := fmt.Sprintf(format, args...)
+	if len(path) == 0 {
+		return fmt.Errorf(`toml: %v`, msg)
+	}
+	return fmt.Errorf(`toml: %v: %v`, path, msg)
+}
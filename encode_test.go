@@ -0,0 +1,484 @@
+package toml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+
+	type server struct {
+		Host string `toml:"host"`
+		Port int    `toml:"port"`
+	}
+
+	type tls struct {
+		Cert string `toml:"cert"`
+	}
+
+	type config struct {
+		Title   string            `toml:"title"`
+		Owner   map[string]string `toml:"owner"`
+		Created time.Time         `toml:"created"`
+		Ratio   float64           `toml:"ratio"`
+		Tags    []string          `toml:"tags"`
+		Servers []server          `toml:"servers"`
+		TLS     *tls              `toml:"tls"`
+		Debug   *bool             `toml:"debug"`
+		Extra   string            `toml:"extra,omitempty"`
+		IP      net.IP            `toml:"ip"`
+		Labels  map[string]string `toml:"tls labels"`
+		Points  []interface{}     `toml:"points"`
+	}
+
+	c := config{
+		Title:   "TOML \"example\"\n",
+		Owner:   map[string]string{`name`: `Tom`, `dept.id`: `7`},
+		Created: time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		Ratio:   2,
+		Tags:    []string{`a`, `b`},
+		Servers: []server{{Host: `alpha`, Port: 8001}, {Host: `beta`, Port: 8002}},
+		TLS:     &tls{Cert: `a.pem`},
+		IP:      net.ParseIP(`10.0.0.1`),
+		Labels:  map[string]string{},
+		Points:  []interface{}{map[string]int{`x`: 1}, 2},
+	}
+
+	data, err := Marshal(c)
+	require.NoError(t, err)
+
+	assert.Equal(t, `title = "TOML \"example\"\n"
+created = 1979-05-27T07:32:00Z
+ratio = 2.0
+tags = ["a", "b"]
+ip = "10.0.0.1"
+points = [{ x = 1 }, 2]
+
+[owner]
+"dept.id" = "7"
+name = "Tom"
+
+[tls]
+cert = "a.pem"
+
+["tls labels"]
+
+[[servers]]
+host = "alpha"
+port = 8001
+
+[[servers]]
+host = "beta"
+port = 8002
+`, string(data))
+
+	var decoded config
+	require.NoError(t, Unmarshal(data, &decoded))
+	assert.Equal(t, c.Servers, decoded.Servers)
+	assert.Equal(t, c.Owner, decoded.Owner)
+	assert.True(t, c.Created.Equal(decoded.Created))
+	assert.Equal(t, c.IP, decoded.IP)
+}
+
+func TestMarshal_nested(t *testing.T) {
+
+	doc := map[string]interface{}{
+		`fruit`: []map[string]interface{}{
+			{
+				`name`:     `apple`,
+				`physical`: map[string]interface{}{`color`: `red`},
+				`variety`:  []map[string]string{{`name`: `red delicious`}},
+			},
+		},
+		`dates`:  []interface{}{LocalDate{Year: 1979, Month: 5, Day: 27}, LocalTime{Hour: 7, Minute: 32}},
+		`floats`: []float64{math.Inf(-1), math.NaN(), 1e300, 0.5},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewEncoder(&buf).Encode(doc))
+
+	assert.Equal(t, `dates = [1979-05-27, 07:32:00]
+floats = [-inf, nan, 1e+300, 0.5]
+
+[[fruit]]
+name = "apple"
+
+[fruit.physical]
+color = "red"
+
+[[fruit.variety]]
+name = "red delicious"
+`, buf.String())
+}
+
+func TestMarshal_errors(t *testing.T) {
+
+	tests := []struct {
+		v   interface{}
+		err string
+	}{
+		{v: 1, err: `toml: cannot encode int as document, need a struct or map`},
+		{v: map[string]interface{}{`a`: map[int]int{1: 1}}, err: `toml: a: cannot encode map with int keys`},
+		{v: map[string]interface{}{`a`: uint64(math.MaxUint64)}, err: `toml: a: integer 18446744073709551615 out of range`},
+		{v: map[string]interface{}{`a`: make(chan int)}, err: `toml: a: cannot encode chan int`},
+	}
+
+	for _, test := range tests {
+		_, err := Marshal(test.v)
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func TestMarshal_specs(t *testing.T) {
+
+	err := filepath.Walk(`spec-tests/tests/valid`, func(path string, info os.FileInfo, e error) error {
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), `.toml`) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		tree, err := DecodeTree(bytes.NewReader(data))
+		if err != nil {
+			return nil
+		}
+
+		styles := []func(e *Encoder){
+			func(e *Encoder) {},
+			func(e *Encoder) {
+				e.SetDottedKeys(true)
+				e.SetStringStyle(LiteralStrings | MultilineStrings)
+			},
+			func(e *Encoder) {
+				e.SetInlineDepth(2)
+				e.SetIndent("\t")
+				e.SetArrayWidth(10)
+				e.SetStringStyle(MultilineStrings)
+			},
+		}
+
+		for _, style := range styles {
+
+			var buf bytes.Buffer
+			enc := NewEncoder(&buf)
+			style(enc)
+
+			err := enc.Encode(tree)
+			require.NoError(t, err, path)
+
+			decoded, err := DecodeTree(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err, `%v\n%s`, path, buf.Bytes())
+
+			assert.Equal(t, normalizeJSON(tree), normalizeJSON(decoded), `%v\n%s`, path, buf.Bytes())
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func (b byteSize) MarshalTOML() ([]byte, error) {
+	if b%(1<<20) == 0 {
+		return []byte(fmt.Sprintf(`"%vMiB"`, b>>20)), nil
+	}
+	return []byte(fmt.Sprint(int64(b))), nil
+}
+
+func (s stringSet) MarshalTOML() ([]byte, error) {
+
+	items := make([]string, 0, len(s))
+	for item := range s {
+		items = append(items, quoteString(item))
+	}
+	sort.Strings(items)
+
+	return []byte(`[` + strings.Join(items, `, `) + `]`), nil
+}
+
+type rawFragment string
+
+func (r rawFragment) MarshalTOML() ([]byte, error) {
+	if r == `` {
+		return nil, errors.New(`empty fragment`)
+	}
+	return []byte(r), nil
+}
+
+func TestMarshal_marshaler(t *testing.T) {
+
+	type config struct {
+		Cache byteSize
+		Limit *byteSize
+		Tags  stringSet
+		Point rawFragment
+	}
+
+	limit := byteSize(512)
+	c := config{
+		Cache: 10 << 20,
+		Limit: &limit,
+		Tags:  stringSet{`b`: true, `a`: true},
+		Point: `{ x = 1, y = 2 }`,
+	}
+
+	data, err := Marshal(c)
+	require.NoError(t, err)
+	assert.Equal(t, `Cache = "10MiB"
+Limit = 512
+Tags = ["a", "b"]
+Point = { x = 1, y = 2 }
+`, string(data))
+
+	var decoded struct {
+		Cache byteSize
+		Limit *byteSize
+		Tags  stringSet
+	}
+	require.NoError(t, Unmarshal(data, &decoded))
+	assert.Equal(t, c.Cache, decoded.Cache)
+	assert.Equal(t, c.Limit, decoded.Limit)
+	assert.Equal(t, c.Tags, decoded.Tags)
+
+	tests := []struct {
+		fragment rawFragment
+		err      string
+	}{
+		{fragment: ``, err: `toml: p: empty fragment`},
+		{fragment: "1\nb = 2", err: `toml: p: MarshalTOML returned invalid value "1\nb = 2": more than one value`},
+		{fragment: `"open`, err: `toml: p: MarshalTOML returned invalid value "\"open": position (0:9) msg: character not allowed in quoted string`},
+		{fragment: `1 # c`, err: `toml: p: MarshalTOML returned invalid value "1 # c": position (0:11) msg: inline table comma not found`},
+		{fragment: `1, 2`, err: `toml: p: MarshalTOML returned invalid value "1, 2": position (0:6) msg: invalid character after value`},
+		{fragment: `1 }, y = { z = 2`, err: `toml: p: MarshalTOML returned invalid value "1 }, y = { z = 2": position (0:7) msg: invalid character after value`},
+	}
+
+	for _, test := range tests {
+		_, err := Marshal(map[string]interface{}{`p`: test.fragment})
+		assert.EqualError(t, err, test.err)
+	}
+
+	// fragments are spliced into arrays and inline tables,
+	// a comment there would swallow the rest of the line
+	_, err = Marshal(map[string]interface{}{`a`: []rawFragment{`1 # c`, `2`}})
+	assert.EqualError(t, err, `toml: a[0]: MarshalTOML returned invalid value "1 # c": position (0:11) msg: inline table comma not found`)
+
+	e := NewEncoder(&bytes.Buffer{})
+	e.SetInlineDepth(1)
+	err = e.Encode(map[string]interface{}{`t`: map[string]rawFragment{`x`: `1 # c`, `y`: `1`}})
+	assert.EqualError(t, err, `toml: t.x: MarshalTOML returned invalid value "1 # c": position (0:11) msg: inline table comma not found`)
+
+	data, err = Marshal(map[string]interface{}{`a`: []rawFragment{`[1, 2]`, `{ x = 1 }`, "\"\"\"a\nb\"\"\""}})
+	require.NoError(t, err)
+	assert.Equal(t, "a = [[1, 2], { x = 1 }, \"\"\"a\nb\"\"\"]\n", string(data))
+}
+
+func TestEncoder_style(t *testing.T) {
+
+	doc := map[string]interface{}{
+		`name`:  "it's",
+		`notes`: "a\n\"b\"\n",
+		`ports`: []int{8001, 8002, 8003},
+		`server`: map[string]interface{}{
+			`host`: `localhost`,
+			`tls`:  map[string]interface{}{`cert`: `a.pem`},
+		},
+		`users`: []map[string]interface{}{{`name`: `a`}},
+	}
+
+	tests := []struct {
+		style    func(e *Encoder)
+		expected string
+	}{
+		{
+			style: func(e *Encoder) {
+				e.SetIndent(`  `)
+				e.SetStringStyle(LiteralStrings)
+			},
+			expected: `name = "it's"
+notes = "a\n\"b\"\n"
+ports = [8001, 8002, 8003]
+
+[server]
+  host = 'localhost'
+
+  [server.tls]
+    cert = 'a.pem'
+
+[[users]]
+  name = 'a'
+`,
+		},
+		{
+			style: func(e *Encoder) {
+				e.SetDottedKeys(true)
+				e.SetStringStyle(LiteralStrings | MultilineStrings)
+			},
+			expected: `name = "it's"
+notes = '''
+a
+"b"
+'''
+ports = [8001, 8002, 8003]
+server.host = 'localhost'
+server.tls.cert = 'a.pem'
+
+[[users]]
+name = 'a'
+`,
+		},
+		{
+			style: func(e *Encoder) {
+				e.SetInlineDepth(2)
+				e.SetIndent(`    `)
+				e.SetArrayWidth(20)
+				e.SetStringStyle(MultilineStrings)
+			},
+			expected: `name = "it's"
+notes = """
+a
+\"b\"
+"""
+ports = [
+    8001,
+    8002,
+    8003,
+]
+
+[server]
+    host = "localhost"
+    tls = { cert = "a.pem" }
+
+[[users]]
+    name = "a"
+`,
+		},
+		{
+			style: func(e *Encoder) {
+				e.SetInlineDepth(1)
+			},
+			expected: `name = "it's"
+notes = "a\n\"b\"\n"
+ports = [8001, 8002, 8003]
+server = { host = "localhost", tls = { cert = "a.pem" } }
+users = [{ name = "a" }]
+`,
+		},
+	}
+
+	for _, test := range tests {
+
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		test.style(enc)
+
+		require.NoError(t, enc.Encode(doc))
+		assert.Equal(t, test.expected, buf.String())
+
+		decoded, err := DecodeTree(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, doc[`notes`], decoded[`notes`])
+	}
+}
+
+func TestEncoder_policy(t *testing.T) {
+
+	type point struct {
+		X int `toml:"x"`
+	}
+
+	type doc struct {
+		Name   *string                `toml:"name"`
+		Values []interface{}          `toml:"values,omitempty"`
+		Mixed  []interface{}          `toml:"mixed,omitempty"`
+		Codes  map[int]string         `toml:"codes,omitempty"`
+		Extra  map[string]interface{} `toml:"extra,omitempty"`
+	}
+
+	d := doc{
+		Values: []interface{}{1, nil, 2},
+		Mixed:  []interface{}{point{X: 1}, 2},
+		Extra:  map[string]interface{}{`a`: nil},
+	}
+
+	tests := []struct {
+		policy   Policy
+		doc      doc
+		expected string
+		err      string
+	}{
+		{
+			doc: d,
+			expected: `values = [1, 2]
+mixed = [{ x = 1 }, 2]
+
+[extra]
+`,
+		},
+		{
+			policy: Policy{Nil: NilError},
+			doc:    d,
+			err:    `toml: name: cannot encode nil`,
+		},
+		{
+			policy: Policy{Nil: NilError},
+			doc:    doc{Name: new(string), Values: d.Values},
+			err:    `toml: values[1]: cannot encode nil`,
+		},
+		{
+			policy: Policy{Nil: NilSentinel, Sentinel: `null`},
+			doc:    d,
+			expected: `name = "null"
+values = [1, "null", 2]
+mixed = [{ x = 1 }, 2]
+
+[extra]
+a = "null"
+`,
+		},
+		{
+			policy: Policy{Mixed: MixedError},
+			doc:    d,
+			err:    `toml: mixed[1]: cannot mix tables and other values`,
+		},
+		{
+			doc: doc{Codes: map[int]string{404: `not found`}},
+			err: `toml: codes: cannot encode map with int keys`,
+		},
+		{
+			policy: Policy{MapKeys: MapKeyString},
+			doc:    doc{Codes: map[int]string{404: `not found`, 200: `ok`}},
+			expected: `[codes]
+200 = "ok"
+404 = "not found"
+`,
+		},
+	}
+
+	for _, test := range tests {
+
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetPolicy(test.policy)
+
+		err := enc.Encode(test.doc)
+		if test.err != `` {
+			assert.EqualError(t, err, test.err)
+			continue
+		}
+
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, buf.String())
+	}
+}
@@ -0,0 +1,125 @@
+package toml
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeError is returned when a value of the document
+// cannot be decoded into its target. Path is the key path of
+// the value, with array elements written as servers[2].
+// Line and Col point to the value in the document.
+type DecodeError struct {
+	Path string
+	Line int
+	Col  int
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == `` {
+		return fmt.Sprintf(`toml: line %v, col %v: %v`, e.Line, e.Col, e.Err)
+	}
+	return fmt.Sprintf(`toml: %v (line %v, col %v): %v`, e.Path, e.Line, e.Col, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+type pathElem struct {
+	key   string
+	index int
+}
+
+// keyPath is the path to a value while decoding. Elements
+// with an empty key are array indexes.
+type keyPath []pathElem
+
+func (p keyPath) key(key string) keyPath {
+	return append(p, pathElem{key: key})
+}
+
+func (p keyPath) index(i int) keyPath {
+	return append(p, pathElem{index: i})
+}
+
+func (p keyPath) String() string {
+
+	var b strings.Builder
+	for i, e := range p {
+
+		if e.key == `` {
+			b.WriteString(`[` + strconv.Itoa(e.index) + `]`)
+			continue
+		}
+
+		if i > 0 {
+			b.WriteString(`.`)
+		}
+		b.WriteString(Key{e.key}.String())
+	}
+	return b.String()
+}
+
+func wrapError(path keyPath, n *node, err error) error {
+	return &DecodeError{Path: path.String(), Line: n.pos.Line, Col: n.pos.Col, Err: err}
+}
+
+func decodeError(path keyPath, n *node, format string, args ...interface{}) error {
+	return wrapError(path, n, fmt.Errorf(format, args...))
+}
+
+func mismatch(n *node, rv reflect.Value, path keyPath) error {
+	return decodeError(path, n, `expected %v, found %v`, expectedType(rv.Type()), found(n))
+}
+
+// expectedType names the TOML type that decodes into t.
+func expectedType(t reflect.Type) string {
+
+	switch t {
+	case timeType:
+		return string(DateTimeType)
+	case localDateTimeType:
+		return string(LocalDateTimeType)
+	case localDateType:
+		return string(LocalDateType)
+	case localTimeType:
+		return string(LocalTimeType)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return string(StringType)
+	case reflect.Bool:
+		return string(BoolType)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return string(IntegerType)
+	case reflect.Float32, reflect.Float64:
+		return string(FloatType)
+	case reflect.Struct, reflect.Map:
+		return string(TableType)
+	case reflect.Slice, reflect.Array:
+		return string(ArrayType)
+	case reflect.Ptr:
+		return expectedType(t.Elem())
+	}
+	return t.String()
+}
+
+func found(n *node) string {
+
+	switch v := n.value.(type) {
+	case string:
+		return fmt.Sprintf(`%v %q`, n.typ, v)
+	case time.Time, LocalDateTime, LocalDate, LocalTime:
+		return fmt.Sprintf(`%v %v`, n.typ, formatDateTime(v))
+	case nil:
+		return string(n.typ)
+	}
+	return fmt.Sprintf(`%v %v`, n.typ, n.value)
+}
@@ -0,0 +1,90 @@
+package toml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeError(t *testing.T) {
+
+	doc := `
+[[servers]]
+port = 80
+[[servers]]
+port = 81
+[[servers]]
+"tls port" = "80"
+port = "80"
+`
+
+	tests := []struct {
+		v   interface{}
+		err string
+	}{
+		{
+			v: &struct {
+				Servers []struct {
+					Port int
+				}
+			}{},
+			err: `toml: servers[2].port (line 8, col 8): expected integer, found string "80"`,
+		},
+		{
+			v: &struct {
+				Servers []struct {
+					TLSPort []int `toml:"tls port"`
+				}
+			}{},
+			err: `toml: servers[2]."tls port" (line 7, col 14): expected array, found string "80"`,
+		},
+		{
+			v: &struct {
+				Servers map[string]int
+			}{},
+			err: `toml: servers (line 2, col 1): expected table, found array-of-tables`,
+		},
+		{
+			v:   &[]int{},
+			err: `toml: line 1, col 1: expected array, found table`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		err := Unmarshal([]byte(doc), ts.v)
+		require.Error(t, err)
+		assert.Equal(t, ts.err, err.Error())
+
+		var decodeErr *DecodeError
+		require.True(t, errors.As(err, &decodeErr))
+	}
+
+	// arrays and inline tables are reported at their
+	// value like scalars
+	var v struct {
+		A string
+		B string
+	}
+	err := Unmarshal([]byte(`a = [1]`), &v)
+	assert.EqualError(t, err, `toml: a (line 1, col 5): expected string, found array`)
+
+	err = Unmarshal([]byte(`b = { x = 1 }`), &v)
+	assert.EqualError(t, err, `toml: b (line 1, col 5): expected string, found inline-table`)
+}
+
+func TestDecodeError_unwrap(t *testing.T) {
+
+	var st struct{ Level logLevel }
+	_, err := NewDecoder(strings.NewReader(`level = "x"`)).Decode(&st)
+
+	var decodeErr *DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, `level`, decodeErr.Path)
+	assert.Equal(t, 1, decodeErr.Line)
+	assert.Equal(t, 9, decodeErr.Col)
+	assert.Equal(t, `unknown log level "x"`, errors.Unwrap(err).Error())
+}
@@ -0,0 +1,742 @@
+package toml
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	toml "github.com/komkom/toml/internal"
+)
+
+// EventUnmarshaler is implemented by the DecodeTOML methods
+// cmd/tomlgen writes. Decode hands the document to them
+// instead of decoding by reflection, unless hooks are
+// registered.
+type EventUnmarshaler interface {
+	DecodeTOML(d *EventDecoder) error
+}
+
+// FieldFunc decodes the value at key of a table into the
+// struct field matching key. The value is read with the
+// methods of d.
+type FieldFunc func(d *EventDecoder, key string) error
+
+// FieldName is the key of a struct field and whether it
+// comes from a tag.
+type FieldName struct {
+	Name   string
+	Tagged bool
+}
+
+// FieldSet lists the keys of the fields of a struct in
+// field order.
+type FieldSet []FieldName
+
+// Match returns the index of the field matching key the way
+// Decode matches them, or -1.
+func (fs FieldSet) Match(key string) int {
+	return matchKey(key, len(fs), func(i int) (string, bool, bool) {
+		return fs[i].Name, fs[i].Tagged, false
+	})
+}
+
+// EventDecoder routes the events of the parser to the code
+// cmd/tomlgen writes. Every table header and every complete
+// key/value pair is passed down from the root FieldFunc,
+// one key at a time, without reflection. Top level scalars
+// are read from their events, other values from the nodes
+// Decode builds as well. The methods read the current value
+// and fail with the errors Decode returns.
+type EventDecoder struct {
+	d       *Decoder
+	b       *builder
+	running bool
+
+	// table is the key of the current table header and
+	// valueKey the key of the value being read in it.
+	root     FieldFunc
+	table    []string
+	valueKey []string
+	keyBuf   []string
+
+	// key holds the keys left to route, header is set
+	// while routing a table header.
+	key    []string
+	header bool
+	n      *node
+	path   keyPath
+
+	// event is the top level scalar being routed. n is nil
+	// while it is the current value, as its node is only built
+	// if a method needs more than its value. The scalars
+	// decoded directly share one node per type in decoded.
+	event   scalarEvent
+	decoded map[Type]*node
+
+	// err is the first decode error. It is returned once
+	// the document is parsed, so syntax errors come first
+	// like for Decode.
+	err error
+
+	// bases are the lengths of the slices when their
+	// arrays were first seen.
+	bases map[*node]int
+
+	// pending values are decoded by reflection once their
+	// tables may be complete.
+	pending    []pendingValue
+	pendingSet map[interface{}]bool
+}
+
+type pendingValue struct {
+	v    interface{}
+	n    *node
+	path keyPath
+}
+
+// scalarEvent is a scalar value of the filter and the table
+// its key is set in.
+type scalarEvent struct {
+	kind     toml.ValueKind
+	fragment []byte
+	start    toml.Position
+	end      toml.Position
+	keyStart int
+
+	parent *node
+	key    string
+	done   bool
+	err    error
+}
+
+// node builds the node of the scalar and sets it in its table.
+func (ev *scalarEvent) node() (*node, error) {
+
+	ev.done = true
+	n, err := scalarNode(ev.kind, ev.fragment, ev.start, ev.end)
+	if err != nil {
+		ev.err = err
+		return nil, err
+	}
+
+	n.keyStart = ev.keyStart
+	ev.parent.set(ev.key, n)
+	return n, nil
+}
+
+func (d *Decoder) decodeEvents(u EventUnmarshaler, b *builder) (MetaData, error) {
+
+	e := &EventDecoder{d: d, b: b, bases: make(map[*node]int), decoded: make(map[Type]*node)}
+	d.violations = nil
+
+	err := u.DecodeTOML(e)
+	if err != nil {
+		return MetaData{}, err
+	}
+
+	if len(d.violations) > 0 {
+		return MetaData{}, &ValidationError{Errors: d.violations}
+	}
+	return newMetaData(b.root), nil
+}
+
+// Run reads the document and passes its keys to root.
+func (e *EventDecoder) Run(root FieldFunc) error {
+
+	e.root = root
+	e.running = true
+	e.b.sink = eventSink{builder: e.b, e: e}
+
+	err := e.b.read(e.d.reader, func() error { return nil })
+	if err != nil {
+		return err
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.flush()
+}
+
+// Reflect decodes the current value into v by reflection,
+// for types the generated code does not handle. Tables are
+// decoded once they may be complete. Called instead of Run,
+// Reflect decodes the whole document.
+func (e *EventDecoder) Reflect(v interface{}) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf(`toml: Reflect needs a non-nil pointer, got %T`, v)
+	}
+
+	if !e.running {
+		err := e.b.read(e.d.reader, func() error { return nil })
+		if err != nil {
+			return err
+		}
+		return e.d.decode(e.b.root, rv.Elem(), nil)
+	}
+
+	if !e.header && len(e.key) == 0 {
+		err := e.build()
+		if err != nil {
+			return err
+		}
+		return e.d.decode(e.n, rv.Elem(), e.path)
+	}
+
+	if e.pendingSet[v] {
+		return nil
+	}
+	if e.pendingSet == nil {
+		e.pendingSet = make(map[interface{}]bool)
+	}
+
+	e.pendingSet[v] = true
+	e.pending = append(e.pending, pendingValue{v: v, n: e.n, path: append(keyPath(nil), e.path...)})
+	e.key = nil
+	return nil
+}
+
+// flush decodes the pending values. It runs before slices
+// grow, as the values can point into them.
+func (e *EventDecoder) flush() error {
+
+	pending := e.pending
+	e.pending, e.pendingSet = nil, nil
+
+	for _, p := range pending {
+		err := e.d.decode(p.n, reflect.ValueOf(p.v).Elem(), p.path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *EventDecoder) route(key []string, header bool) error {
+
+	e.key = key
+	e.header = header
+	e.n = e.b.root
+	e.path = e.path[:0]
+	return e.Table(e.root)
+}
+
+// Table decodes the current table with fn, which is called
+// with its keys.
+func (e *EventDecoder) Table(fn FieldFunc) error {
+
+	err := e.build()
+	if err != nil {
+		return err
+	}
+
+	n := e.n
+	if !n.typ.isTable() {
+		return e.mismatch(TableType)
+	}
+	n.decoded = true
+
+	if len(e.key) > 0 {
+		key := e.key[0]
+		e.key = e.key[1:]
+		return e.field(n, key, fn)
+	}
+
+	if e.header {
+		return nil
+	}
+
+	for _, key := range n.keys {
+		err := e.field(n, key, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *EventDecoder) field(n *node, key string, fn FieldFunc) error {
+
+	// n has no field yet for the top level scalar being
+	// routed, leaving e.n nil
+	depth := len(e.path)
+	e.n, e.path = n.fields[key], e.path.key(key)
+
+	err := fn(e, key)
+
+	// truncating keeps the grown path for the next key
+	e.n, e.path = n, e.path[:depth]
+	return err
+}
+
+// Slice decodes the current array or array of tables. length
+// is the length of the slice, resize sets it to n elements,
+// keeping the first ones and adding zero values. The slice
+// is emptied first unless appendTo is set. elem decodes
+// element i.
+func (e *EventDecoder) Slice(appendTo bool, length int, resize func(n int), elem func(i int) error) error {
+
+	err := e.build()
+	if err != nil {
+		return err
+	}
+
+	n := e.n
+	if !n.typ.isArray() {
+		return e.mismatch(ArrayType)
+	}
+	n.decoded = true
+
+	base, ok := e.bases[n]
+	if !ok {
+		if !appendTo {
+			length = 0
+		}
+		base = length
+		e.bases[n] = base
+
+		err := e.flush()
+		if err != nil {
+			return err
+		}
+		resize(base)
+	}
+
+	if want := base + len(n.items); want != length {
+		err := e.flush()
+		if err != nil {
+			return err
+		}
+		resize(want)
+	}
+
+	if n.typ == ArrayOfTablesType {
+		// keys go to the last element
+		return e.item(n, len(n.items)-1, base, elem)
+	}
+
+	for i := range n.items {
+		err := e.item(n, i, base, elem)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *EventDecoder) item(n *node, i int, base int, elem func(i int) error) error {
+
+	depth := len(e.path)
+	e.n, e.path = n.items[i], e.path.index(i)
+
+	err := elem(base + i)
+
+	e.n, e.path = n, e.path[:depth]
+	return err
+}
+
+// Unknown handles a key matching no field. It fails if
+// unknown fields are disallowed.
+func (e *EventDecoder) Unknown(key string) error {
+
+	if e.d.strict {
+		err := e.build()
+		if err != nil {
+			return err
+		}
+		return decodeError(e.path, e.n, `unknown field %q`, key)
+	}
+	e.key = nil
+	return nil
+}
+
+// StringValue returns the current value as string. Date-times
+// are returned in RFC 3339 form, local ones without offset.
+func (e *EventDecoder) StringValue() (string, error) {
+
+	if e.scalar() == StringType && bytes.IndexByte(e.event.fragment, '\\') == -1 {
+		e.consume(StringType)
+		return string(e.event.fragment[1 : len(e.event.fragment)-1]), nil
+	}
+
+	err := e.build()
+	if err != nil {
+		return ``, err
+	}
+
+	switch e.n.typ {
+	case StringType, DateTimeType, LocalDateTimeType, LocalDateType, LocalTimeType:
+		e.n.decoded = true
+		return formatDateTime(e.n.value), nil
+	}
+	return ``, e.mismatch(StringType)
+}
+
+// BoolValue returns the current value as bool.
+func (e *EventDecoder) BoolValue() (bool, error) {
+
+	if e.scalar() == BoolType {
+		e.consume(BoolType)
+		return string(e.event.fragment) == `true`, nil
+	}
+
+	err := e.build()
+	if err != nil {
+		return false, err
+	}
+
+	if e.n.typ != BoolType {
+		return false, e.mismatch(BoolType)
+	}
+	e.n.decoded = true
+	return e.n.value.(bool), nil
+}
+
+// IntValue returns the current value as integer of the given
+// bit size, 0 being the size of int. typ names the Go type in
+// errors.
+func (e *EventDecoder) IntValue(typ string, bits int) (int64, error) {
+
+	i, err := e.integer()
+	if err != nil {
+		return 0, err
+	}
+
+	if bits == 0 {
+		bits = strconv.IntSize
+	}
+
+	if bits < 64 && (i < -1<<(bits-1) || i >= 1<<(bits-1)) {
+		return 0, e.errorf(`integer %v overflows %v`, i, typ)
+	}
+	return i, nil
+}
+
+// UintValue returns the current value as unsigned integer
+// like IntValue does.
+func (e *EventDecoder) UintValue(typ string, bits int) (uint64, error) {
+
+	i, err := e.integer()
+	if err != nil {
+		return 0, err
+	}
+
+	if bits == 0 {
+		bits = strconv.IntSize
+	}
+
+	if i < 0 || bits < 64 && uint64(i) >= 1<<bits {
+		return 0, e.errorf(`integer %v overflows %v`, i, typ)
+	}
+	return uint64(i), nil
+}
+
+func (e *EventDecoder) integer() (int64, error) {
+
+	if e.scalar() == IntegerType {
+		i, err := strconv.ParseInt(string(e.event.fragment), 10, 64)
+		if err == nil {
+			e.consume(IntegerType)
+			return i, nil
+		}
+	}
+
+	err := e.build()
+	if err != nil {
+		return 0, err
+	}
+
+	if e.n.typ != IntegerType {
+		return 0, e.mismatch(IntegerType)
+	}
+	e.n.decoded = true
+	return e.n.value.(int64), nil
+}
+
+// FloatValue returns the current value as float of the given
+// bit size. Integers are converted. typ names the Go type in
+// errors.
+func (e *EventDecoder) FloatValue(typ string, bits int) (float64, error) {
+
+	f, err := e.float()
+	if err != nil {
+		return 0, err
+	}
+
+	if bits == 32 && overflowFloat32(f) {
+		return 0, e.errorf(`float %v overflows %v`, f, typ)
+	}
+	return f, nil
+}
+
+// overflowFloat32 tells whether the finite f is out of the
+// range of float32, like reflect.Value.OverflowFloat.
+func overflowFloat32(f float64) bool {
+
+	if f < 0 {
+		f = -f
+	}
+	return math.MaxFloat32 < f && f <= math.MaxFloat64
+}
+
+func (e *EventDecoder) float() (float64, error) {
+
+	switch e.scalar() {
+	case FloatType:
+		if e.event.kind != toml.SpecialKind {
+			f, err := strconv.ParseFloat(string(e.event.fragment), 64)
+			if err == nil {
+				e.consume(FloatType)
+				return f, nil
+			}
+		}
+	case IntegerType:
+		i, err := e.integer()
+		return float64(i), err
+	}
+
+	err := e.build()
+	if err != nil {
+		return 0, err
+	}
+
+	switch e.n.typ {
+	case FloatType:
+		e.n.decoded = true
+		return e.n.value.(float64), nil
+	case IntegerType:
+		e.n.decoded = true
+		return float64(e.n.value.(int64)), nil
+	}
+	return 0, e.mismatch(FloatType)
+}
+
+// TimeValue returns the current offset date-time. Local
+// date-times and dates are accepted once the decoder has
+// a location. Like for the local types, strings are parsed
+// with UnmarshalText.
+func (e *EventDecoder) TimeValue() (time.Time, error) {
+
+	var t time.Time
+	if ok, err := e.text(&t); ok {
+		return t, err
+	}
+
+	if e.d.location != nil {
+		switch v := e.n.value.(type) {
+		case LocalDateTime:
+			e.n.decoded = true
+			return v.In(e.d.location), nil
+		case LocalDate:
+			e.n.decoded = true
+			return v.In(e.d.location), nil
+		}
+	}
+
+	v, ok := e.n.value.(time.Time)
+	if !ok {
+		return time.Time{}, e.mismatch(DateTimeType)
+	}
+	e.n.decoded = true
+	return v, nil
+}
+
+// LocalDateTimeValue returns the current local date-time.
+func (e *EventDecoder) LocalDateTimeValue() (LocalDateTime, error) {
+
+	var v LocalDateTime
+	if ok, err := e.text(&v); ok {
+		return v, err
+	}
+
+	v, ok := e.n.value.(LocalDateTime)
+	if !ok {
+		return LocalDateTime{}, e.mismatch(LocalDateTimeType)
+	}
+	e.n.decoded = true
+	return v, nil
+}
+
+// LocalDateValue returns the current local date.
+func (e *EventDecoder) LocalDateValue() (LocalDate, error) {
+
+	var v LocalDate
+	if ok, err := e.text(&v); ok {
+		return v, err
+	}
+
+	v, ok := e.n.value.(LocalDate)
+	if !ok {
+		return LocalDate{}, e.mismatch(LocalDateType)
+	}
+	e.n.decoded = true
+	return v, nil
+}
+
+// LocalTimeValue returns the current local time.
+func (e *EventDecoder) LocalTimeValue() (LocalTime, error) {
+
+	var v LocalTime
+	if ok, err := e.text(&v); ok {
+		return v, err
+	}
+
+	v, ok := e.n.value.(LocalTime)
+	if !ok {
+		return LocalTime{}, e.mismatch(LocalTimeType)
+	}
+	e.n.decoded = true
+	return v, nil
+}
+
+// text decodes strings with UnmarshalText, as Decode does
+// for all types implementing encoding.TextUnmarshaler.
+func (e *EventDecoder) text(u encoding.TextUnmarshaler) (bool, error) {
+
+	err := e.build()
+	if err != nil {
+		return true, err
+	}
+
+	if e.n.typ != StringType {
+		return false, nil
+	}
+	e.n.decoded = true
+
+	err = u.UnmarshalText([]byte(e.n.value.(string)))
+	if err != nil {
+		return true, wrapError(e.path, e.n, err)
+	}
+	return true, nil
+}
+
+func (e *EventDecoder) mismatch(expected Type) error {
+	return decodeError(e.path, e.n, `expected %v, found %v`, expected, found(e.n))
+}
+
+// errorf returns a decode error at the current value.
+func (e *EventDecoder) errorf(format string, args ...interface{}) error {
+
+	n := e.n
+	if n == nil {
+		n = &node{pos: e.event.start}
+	}
+	return decodeError(e.path, n, format, args...)
+}
+
+// scalar returns the type of the current value if it is the
+// top level scalar being routed and has no node.
+func (e *EventDecoder) scalar() Type {
+
+	if e.n != nil || e.event.done {
+		return InvalidType
+	}
+	return Type(toml.ScalarType(e.event.kind, e.event.fragment))
+}
+
+// consume marks the scalar being routed as decoded, setting
+// the shared node of typ at its key.
+func (e *EventDecoder) consume(typ Type) {
+
+	n, ok := e.decoded[typ]
+	if !ok {
+		n = &node{typ: typ, decoded: true}
+		e.decoded[typ] = n
+	}
+
+	e.event.done = true
+	e.event.parent.set(e.event.key, n)
+}
+
+// build builds the node of the scalar being routed, for
+// the methods needing more than its value.
+func (e *EventDecoder) build() error {
+
+	if e.n != nil {
+		return nil
+	}
+
+	n, err := e.event.node()
+	if err != nil {
+		return err
+	}
+	e.n = n
+	return nil
+}
+
+// eventSink builds the tree and routes table headers and
+// complete key/value pairs to the EventDecoder.
+type eventSink struct {
+	*builder
+	e *EventDecoder
+}
+
+func (s eventSink) Table(key []string, v toml.Var, start toml.Position, end toml.Position) {
+	s.builder.Table(key, v, start, end)
+	s.e.table = unescapeKey(key)
+	s.route(s.e.table, true)
+}
+
+func (s eventSink) Key(key []string, pos toml.Position) {
+	s.builder.Key(key, pos)
+	if len(s.stack) == 0 {
+		s.e.valueKey = s.builder.key
+	}
+}
+
+func (s eventSink) Close(kind toml.ValueKind, pos toml.Position) {
+	s.builder.Close(kind, pos)
+	if len(s.stack) == 0 {
+		s.routeValue()
+	}
+}
+
+// Value routes top level scalars before building their
+// node, which is left out for the ones the generated code
+// decodes directly.
+func (s eventSink) Value(kind toml.ValueKind, fragment []byte, start toml.Position, end toml.Position) {
+
+	if len(s.stack) > 0 || s.err != nil {
+		s.builder.Value(kind, fragment, start, end)
+		return
+	}
+
+	ev := &s.e.event
+	*ev = scalarEvent{
+		kind:     kind,
+		fragment: fragment,
+		start:    start,
+		end:      end,
+		keyStart: s.keyPos.Offset,
+		parent:   s.parent(),
+		key:      s.key[len(s.key)-1],
+	}
+	s.routeValue()
+
+	if !ev.done {
+		ev.node()
+	}
+
+	// conversion errors fail the parse, like in the builder
+	if ev.err != nil {
+		s.err = ev.err
+	}
+}
+
+func (s eventSink) routeValue() {
+
+	s.e.keyBuf = append(append(s.e.keyBuf[:0], s.e.table...), s.e.valueKey...)
+	s.route(s.e.keyBuf, false)
+}
+
+func (s eventSink) route(key []string, header bool) {
+	if s.e.err == nil {
+		s.e.err = s.e.route(key, header)
+	}
+}
@@ -40,3 +40,27 @@ toml="doc"
 
 	// Output: toml: doc is_marked: true
 }
+
+func ExampleUnmarshal() {
+
+	doc := `
+[server]
+host = "localhost"
+server_port = 8080`
+
+	st := struct {
+		Server struct {
+			Host       string `toml:"host"`
+			ServerPort int
+		} `toml:"server"`
+	}{}
+
+	err := toml.Unmarshal([]byte(doc), &st)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%v:%v", st.Server.Host, st.Server.ServerPort)
+
+	// Output: localhost:8080
+}
@@ -0,0 +1,209 @@
+package toml
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type field struct {
+	name      string
+	tagged    bool
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+	remain    bool
+	comment   string
+
+	// defaultValue is used for keys missing from
+	// the document if hasDefault is set.
+	defaultValue string
+	hasDefault   bool
+
+	// appendSlice appends arrays to the slice instead
+	// of replacing it.
+	appendSlice bool
+
+	// rules are the checks of the validate tag.
+	rules    []rule
+	rulesErr error
+}
+
+type fields []field
+
+var fieldCache sync.Map // map[reflect.Type]fields
+
+// cachedFields returns the fields of the struct type t.
+func cachedFields(t reflect.Type) fields {
+
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.(fields)
+	}
+
+	fs, _ := fieldCache.LoadOrStore(t, typeFields(t, nil))
+	return fs.(fields)
+}
+
+func typeFields(t reflect.Type, index []int) fields {
+
+	var fs fields
+	for i := 0; i < t.NumField(); i++ {
+
+		sf := t.Field(i)
+
+		tag, ok := sf.Tag.Lookup(`toml`)
+		if !ok {
+			tag = sf.Tag.Get(`json`)
+		}
+
+		if tag == `-` {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		if sf.Anonymous && name == `` && sf.Type.Kind() == reflect.Struct {
+			fs = append(fs, typeFields(sf.Type, idx)...)
+			continue
+		}
+
+		if sf.PkgPath != `` {
+			continue
+		}
+
+		f := field{
+			name:      name,
+			tagged:    name != ``,
+			index:     idx,
+			typ:       sf.Type,
+			omitEmpty: opts.contains(`omitempty`),
+			remain:    opts.contains(`remain`),
+			comment:   sf.Tag.Get(`comment`),
+
+			appendSlice: opts.contains(`append`),
+		}
+
+		f.defaultValue, f.hasDefault = sf.Tag.Lookup(`default`)
+		f.rules, f.rulesErr = parseRules(sf.Tag.Get(`validate`))
+
+		if f.name == `` {
+			f.name = sf.Name
+		}
+
+		fs = append(fs, f)
+	}
+	return fs
+}
+
+// byKey looks up the field for a TOML key. Exact matches win
+// over case insensitive ones. Untagged fields also match
+// snake_case and kebab-case keys.
+func (fs fields) byKey(key string) (field, bool) {
+
+	i := matchKey(key, len(fs), func(i int) (string, bool, bool) {
+		return fs[i].name, fs[i].tagged, fs[i].remain
+	})
+	if i < 0 {
+		return field{}, false
+	}
+	return fs[i], true
+}
+
+// matchKey returns the index of the field matching key, or
+// -1. field returns the name of field i, whether it is tagged
+// and whether it is skipped.
+func matchKey(key string, count int, field func(i int) (string, bool, bool)) int {
+
+	for i := 0; i < count; i++ {
+		if name, _, skip := field(i); !skip && name == key {
+			return i
+		}
+	}
+
+	for i := 0; i < count; i++ {
+		if name, _, skip := field(i); !skip && strings.EqualFold(name, key) {
+			return i
+		}
+	}
+
+	norm := normalizeKey(key)
+	for i := 0; i < count; i++ {
+		if name, tagged, skip := field(i); !skip && !tagged && strings.EqualFold(name, norm) {
+			return i
+		}
+	}
+	return -1
+}
+
+// remain returns the field tagged `toml:",remain"`.
+func (fs fields) remain() (field, bool) {
+	for _, f := range fs {
+		if f.remain {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func normalizeKey(key string) string {
+	return strings.NewReplacer(`_`, ``, `-`, ``).Replace(key)
+}
+
+// key returns the TOML key of the field for keys missing from
+// the document: the tag name, or the field name in snake_case.
+func (f field) key() string {
+	if f.tagged {
+		return f.name
+	}
+	return snakeCase(f.name)
+}
+
+// snakeCase turns a Go name like DBHost into db_host.
+func snakeCase(name string) string {
+
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && next {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+type tagOptions string
+
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.Index(tag, `,`); idx != -1 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, ``
+}
+
+func (o tagOptions) contains(name string) bool {
+
+	s := string(o)
+	for s != `` {
+		var next string
+		if idx := strings.Index(s, `,`); idx >= 0 {
+			s, next = s[:idx], s[idx+1:]
+		}
+		if s == name {
+			return true
+		}
+		s = next
+	}
+	return false
+}
@@ -0,0 +1,322 @@
+package toml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FromJSON wraps an io.Reader around a JSON stream. Reading
+// from it converts the JSON object read from reader into a
+// TOML document, the inverse of New.
+//
+// The conversion streams and only holds the path to the
+// current value. Nested objects are written as dotted keys
+// and arrays inline, with objects in arrays as inline tables
+// on lines of their own. Integers out of the range of int64
+// become floats. JSON null has no TOML counterpart and
+// fails the conversion, FromJSONWithPolicy can choose
+// otherwise.
+func FromJSON(reader io.Reader) io.Reader {
+	return FromJSONWithPolicy(reader, Policy{Nil: NilError})
+}
+
+// FromJSONWithPolicy is FromJSON with policy deciding how null
+// and arrays mixing objects and other values are written.
+func FromJSONWithPolicy(reader io.Reader, policy Policy) io.Reader {
+
+	dec := json.NewDecoder(reader)
+	dec.UseNumber()
+
+	return &jsonReader{decoder: dec, policy: policy}
+}
+
+type jsonReader struct {
+	decoder *json.Decoder
+	policy  Policy
+	buf     bytes.Buffer
+	scopes  []jsonScope
+	started bool
+	done    bool
+	err     error
+}
+
+type jsonScopeKind int
+
+const (
+	dottedScope jsonScopeKind = iota
+	inlineScope
+	arrayScope
+)
+
+// jsonScope is an open JSON object or array. Objects outside
+// arrays are dotted scopes, their members are written with
+// the full key.
+type jsonScope struct {
+	kind  jsonScopeKind
+	key   Key
+	count int
+
+	// member is the key of the current object member
+	// and hasMember whether it still awaits its value.
+	member    string
+	hasMember bool
+
+	// index counts the elements of arrays including
+	// omitted ones, tables is set if the elements
+	// written are tables.
+	index  int
+	tables bool
+
+	// lines is set once an array puts its elements on
+	// lines of their own.
+	lines bool
+}
+
+func (r *jsonReader) Read(p []byte) (int, error) {
+
+	for r.buf.Len() < len(p) && !r.done && r.err == nil {
+		r.err = r.next()
+	}
+
+	if r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		return 0, io.EOF
+	}
+	return r.buf.Read(p)
+}
+
+func (r *jsonReader) next() error {
+
+	token, err := r.decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = fmt.Errorf(`toml: unexpected end of JSON`)
+		}
+		return err
+	}
+
+	if !r.started {
+		r.started = true
+		if token != json.Delim('{') {
+			return fmt.Errorf(`toml: JSON document is not an object`)
+		}
+		r.scopes = append(r.scopes, jsonScope{kind: dottedScope})
+		return nil
+	}
+
+	scope := &r.scopes[len(r.scopes)-1]
+
+	if scope.kind != arrayScope && !scope.hasMember {
+		if token == json.Delim('}') {
+			return r.closeObject()
+		}
+
+		scope.member = token.(string)
+		scope.hasMember = true
+		return nil
+	}
+
+	if token == json.Delim(']') {
+		return r.closeArray()
+	}
+
+	if token == nil {
+		switch r.policy.Nil {
+		case NilError:
+			return fmt.Errorf(`toml: cannot convert null at %v`, r.pointer())
+		case NilSentinel:
+			token = r.policy.Sentinel
+		default:
+			scope.hasMember = false
+			scope.index++
+			return nil
+		}
+	}
+
+	switch scope.kind {
+	case dottedScope:
+		key := append(append(Key(nil), scope.key...), scope.member)
+		scope.hasMember = false
+		scope.count++
+
+		if token == json.Delim('{') {
+			r.scopes = append(r.scopes, jsonScope{kind: dottedScope, key: key})
+			return nil
+		}
+		r.buf.WriteString(key.String() + ` = `)
+
+	case inlineScope:
+		if scope.count > 0 {
+			r.buf.WriteString(`, `)
+		} else {
+			r.buf.WriteString(` `)
+		}
+		r.buf.WriteString(Key{scope.member}.String() + ` = `)
+		scope.hasMember = false
+		scope.count++
+
+	case arrayScope:
+		table := token == json.Delim('{')
+		if r.policy.Mixed == MixedError && scope.count > 0 && table != scope.tables {
+			return fmt.Errorf(`toml: cannot mix objects and other values at %v`, r.pointer())
+		}
+		scope.tables = table
+		scope.index++
+
+		if table && !r.inInlineTable() {
+			scope.lines = true
+		}
+
+		switch {
+		case scope.lines && scope.count > 0:
+			r.buf.WriteString(",\n" + r.indent())
+		case scope.lines:
+			r.buf.WriteString("\n" + r.indent())
+		case scope.count > 0:
+			r.buf.WriteString(`, `)
+		}
+		scope.count++
+	}
+
+	return r.value(token)
+}
+
+func (r *jsonReader) value(token json.Token) error {
+
+	switch v := token.(type) {
+	case json.Delim:
+		if v == '{' {
+			r.buf.WriteString(`{`)
+			r.scopes = append(r.scopes, jsonScope{kind: inlineScope})
+			return nil
+		}
+		r.buf.WriteString(`[`)
+		r.scopes = append(r.scopes, jsonScope{kind: arrayScope})
+		return nil
+
+	case string:
+		r.buf.WriteString(quoteString(v))
+
+	case bool:
+		r.buf.WriteString(strconv.FormatBool(v))
+
+	case json.Number:
+		r.buf.WriteString(jsonNumber(v))
+	}
+
+	r.endValue()
+	return nil
+}
+
+// endValue ends the line of values in dotted scopes.
+func (r *jsonReader) endValue() {
+	if r.scopes[len(r.scopes)-1].kind == dottedScope {
+		r.buf.WriteString("\n")
+	}
+}
+
+func (r *jsonReader) closeObject() error {
+
+	scope := r.scopes[len(r.scopes)-1]
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	if len(r.scopes) == 0 {
+		r.done = true
+		return nil
+	}
+
+	switch {
+	case scope.kind == inlineScope && scope.count > 0:
+		r.buf.WriteString(` }`)
+	case scope.kind == inlineScope:
+		r.buf.WriteString(`}`)
+	case scope.count > 0:
+		return nil
+	default:
+		// empty objects have no dotted keys
+		// to define them
+		r.buf.WriteString(scope.key.String() + " = {}")
+	}
+
+	r.endValue()
+	return nil
+}
+
+func (r *jsonReader) closeArray() error {
+
+	scope := r.scopes[len(r.scopes)-1]
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	if scope.lines {
+		r.buf.WriteString(",\n" + r.indent())
+	}
+	r.buf.WriteString(`]`)
+
+	r.endValue()
+	return nil
+}
+
+func (r *jsonReader) inInlineTable() bool {
+	for _, s := range r.scopes {
+		if s.kind == inlineScope {
+			return true
+		}
+	}
+	return false
+}
+
+// indent returns the indentation for elements of
+// the innermost array.
+func (r *jsonReader) indent() string {
+
+	depth := 0
+	for _, s := range r.scopes {
+		if s.kind == arrayScope {
+			depth++
+		}
+	}
+	return strings.Repeat(`  `, depth)
+}
+
+// pointer returns the JSON pointer of the current value.
+func (r *jsonReader) pointer() string {
+
+	var b strings.Builder
+	for i, s := range r.scopes {
+		b.WriteString(`/`)
+		if s.kind == arrayScope {
+			// enclosing arrays already counted
+			// the element holding the value
+			index := s.index
+			if i < len(r.scopes)-1 {
+				index--
+			}
+			b.WriteString(strconv.Itoa(index))
+			continue
+		}
+		b.WriteString(strings.NewReplacer(`~`, `~0`, `/`, `~1`).Replace(s.member))
+	}
+	return b.String()
+}
+
+// jsonNumber returns n as TOML integer or float.
+func jsonNumber(n json.Number) string {
+
+	s := n.String()
+	if strings.ContainsAny(s, `.eE`) {
+		return s
+	}
+
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return s
+	}
+
+	f, _ := strconv.ParseFloat(s, 64)
+	return formatFloat(f, 64)
+}
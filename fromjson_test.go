@@ -0,0 +1,157 @@
+package toml
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromJSON(t *testing.T) {
+
+	tests := []struct {
+		json     string
+		expected string
+		err      string
+	}{
+		{
+			json: `{"title":"a\"b","owner":{"name":"Tom","dob":"1979-05-27T07:32:00Z"},"ports":[8001,8002],"b":true}`,
+			expected: `title = "a\"b"
+owner.name = "Tom"
+owner.dob = "1979-05-27T07:32:00Z"
+ports = [8001, 8002]
+b = true
+`,
+		},
+		{
+			json: `{"fruit":[{"name":"apple","physical":{"color":"red"},"variety":[{"name":"red delicious"}]},{}],"empty":{},"nested":[[1],[{"a":[]}]]}`,
+			expected: `fruit = [
+  { name = "apple", physical = { color = "red" }, variety = [{ name = "red delicious" }] },
+  {},
+]
+empty = {}
+nested = [[1], [
+    { a = [] },
+  ]]
+`,
+		},
+		{
+			json:     `{"a b":{"c.d":1.5e3},"big":12345678901234567890}`,
+			expected: "\"a b\".\"c.d\" = 1.5e3\nbig = 1.2345678901234567e+19\n",
+		},
+		{
+			json: `{"a":[1,{"b/c":[null]}]}`,
+			err:  `toml: cannot convert null at /a/1/b~1c/0`,
+		},
+		{
+			json: `[1]`,
+			err:  `toml: JSON document is not an object`,
+		},
+		{
+			json: `{"a":1`,
+			err:  `toml: unexpected end of JSON`,
+		},
+	}
+
+	for _, test := range tests {
+
+		data, err := io.ReadAll(FromJSON(strings.NewReader(test.json)))
+		if test.err != `` {
+			assert.EqualError(t, err, test.err)
+			continue
+		}
+
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, string(data))
+	}
+}
+
+func TestFromJSON_specs(t *testing.T) {
+
+	err := filepath.Walk(`spec-tests/tests/valid`, func(path string, info os.FileInfo, e error) error {
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), `.toml`) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		parsedJSON, err := io.ReadAll(New(bytes.NewReader(data)))
+		if err != nil {
+			return nil
+		}
+
+		converted, err := io.ReadAll(FromJSON(bytes.NewReader(parsedJSON)))
+		require.NoError(t, err, path)
+
+		roundTrip, err := io.ReadAll(New(bytes.NewReader(converted)))
+		require.NoError(t, err, `%v\n%s`, path, converted)
+
+		var expected, actual interface{}
+		require.NoError(t, json.Unmarshal(parsedJSON, &expected))
+		require.NoError(t, json.Unmarshal(roundTrip, &actual), `%v\n%s`, path, roundTrip)
+
+		assert.Equal(t, expected, actual, path)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestFromJSONWithPolicy(t *testing.T) {
+
+	doc := `{"a":null,"b":{"c":null},"d":[1,null,2],"e":[{"f":null},3]}`
+
+	tests := []struct {
+		policy   Policy
+		expected string
+		err      string
+	}{
+		{
+			expected: `b = {}
+d = [1, 2]
+e = [
+  {},
+  3,
+]
+`,
+		},
+		{
+			policy: Policy{Nil: NilError},
+			err:    `toml: cannot convert null at /a`,
+		},
+		{
+			policy: Policy{Nil: NilSentinel, Sentinel: `<nil>`},
+			expected: `a = "<nil>"
+b.c = "<nil>"
+d = [1, "<nil>", 2]
+e = [
+  { f = "<nil>" },
+  3,
+]
+`,
+		},
+		{
+			policy: Policy{Mixed: MixedError},
+			err:    `toml: cannot mix objects and other values at /e/1`,
+		},
+	}
+
+	for _, test := range tests {
+
+		data, err := io.ReadAll(FromJSONWithPolicy(strings.NewReader(doc), test.policy))
+		if test.err != `` {
+			assert.EqualError(t, err, test.err)
+			continue
+		}
+
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, string(data))
+	}
+}
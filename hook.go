@@ -0,0 +1,145 @@
+package toml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// HookFunc converts a decoded TOML value into a value
+// of the type the hook was registered for.
+type HookFunc func(value interface{}) (interface{}, error)
+
+// Hook converts TOML values of type From into Go values of
+// type To while decoding. From is one of the types DecodeTree
+// returns. An interface type as From matches every value
+// implementing it.
+type Hook struct {
+	From reflect.Type
+	To   reflect.Type
+	Func HookFunc
+}
+
+var (
+	stringType = reflect.TypeOf(``)
+
+	// DurationHook parses strings like "1m30s" into time.Duration.
+	DurationHook = Hook{
+		From: stringType,
+		To:   reflect.TypeOf(time.Duration(0)),
+		Func: func(value interface{}) (interface{}, error) {
+			return time.ParseDuration(value.(string))
+		},
+	}
+
+	// URLHook parses strings into *url.URL.
+	URLHook = Hook{
+		From: stringType,
+		To:   reflect.TypeOf(&url.URL{}),
+		Func: func(value interface{}) (interface{}, error) {
+			return url.Parse(value.(string))
+		},
+	}
+
+	// RegexpHook compiles strings into *regexp.Regexp.
+	RegexpHook = Hook{
+		From: stringType,
+		To:   reflect.TypeOf(&regexp.Regexp{}),
+		Func: func(value interface{}) (interface{}, error) {
+			return regexp.Compile(value.(string))
+		},
+	}
+
+	// Base64Hook decodes standard base64 strings into []byte.
+	Base64Hook = Hook{
+		From: stringType,
+		To:   reflect.TypeOf([]byte{}),
+		Func: func(value interface{}) (interface{}, error) {
+			return base64.StdEncoding.DecodeString(value.(string))
+		},
+	}
+
+	// IPNetHook parses CIDR strings like "10.0.0.0/8" into net.IPNet.
+	IPNetHook = Hook{
+		From: stringType,
+		To:   reflect.TypeOf(net.IPNet{}),
+		Func: func(value interface{}) (interface{}, error) {
+			_, ipNet, err := net.ParseCIDR(value.(string))
+			if err != nil {
+				return nil, err
+			}
+			return *ipNet, nil
+		},
+	}
+)
+
+// RegisterHook makes the decoder convert values of type from
+// with fn whenever it decodes into type to. Hooks are checked
+// before Unmarshaler and reflection. A later hook for the same
+// types replaces an earlier one.
+func (d *Decoder) RegisterHook(from reflect.Type, to reflect.Type, fn HookFunc) {
+	d.UseHooks(Hook{From: from, To: to, Func: fn})
+}
+
+// UseHooks registers hooks, for example the built-in
+// DurationHook or URLHook.
+func (d *Decoder) UseHooks(hooks ...Hook) {
+
+	for _, h := range hooks {
+		if d.hooks == nil {
+			d.hooks = make(map[reflect.Type][]Hook)
+		}
+		d.hooks[h.To] = append([]Hook{h}, d.hooks[h.To]...)
+	}
+}
+
+func (d *Decoder) hook(n *node, to reflect.Type) (HookFunc, bool) {
+
+	hooks, ok := d.hooks[to]
+	if !ok {
+		return nil, false
+	}
+
+	from := n.goType()
+	for _, h := range hooks {
+
+		if h.From == from {
+			return h.Func, true
+		}
+
+		if h.From != nil && h.From.Kind() == reflect.Interface && from.Implements(h.From) {
+			return h.Func, true
+		}
+	}
+	return nil, false
+}
+
+func (d *Decoder) decodeHook(n *node, rv reflect.Value, fn HookFunc, path keyPath) error {
+
+	n.markDecoded()
+
+	res, err := fn(n.interfaceValue())
+	if err != nil {
+		return wrapError(path, n, err)
+	}
+
+	v := reflect.ValueOf(res)
+	if !v.IsValid() {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	if !v.Type().AssignableTo(rv.Type()) {
+		if !v.Type().ConvertibleTo(rv.Type()) {
+			return wrapError(path, n, fmt.Errorf(`hook returned %v for %v`, v.Type(), rv.Type()))
+		}
+		v = v.Convert(rv.Type())
+	}
+
+	rv.Set(v)
+	return nil
+}
@@ -0,0 +1,86 @@
+package toml
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder_hooks(t *testing.T) {
+
+	doc := `
+timeout = "1m30s"
+endpoint = "https://example.com/api"
+pattern = "^a+$"
+secret = "aGVsbG8="
+network = "10.0.0.0/8"
+retry = 3
+`
+
+	var st struct {
+		Timeout  time.Duration
+		Endpoint *url.URL
+		Pattern  *regexp.Regexp
+		Secret   []byte
+		Network  net.IPNet
+		Retry    time.Duration
+	}
+
+	dec := NewDecoder(strings.NewReader(doc))
+	dec.UseHooks(DurationHook, URLHook, RegexpHook, Base64Hook, IPNetHook)
+	dec.RegisterHook(reflect.TypeOf(int64(0)), reflect.TypeOf(time.Duration(0)), func(value interface{}) (interface{}, error) {
+		return time.Duration(value.(int64)) * time.Second, nil
+	})
+
+	md, err := dec.Decode(&st)
+	require.NoError(t, err)
+
+	assert.Equal(t, 90*time.Second, st.Timeout)
+	assert.Equal(t, `example.com`, st.Endpoint.Host)
+	assert.True(t, st.Pattern.MatchString(`aaa`))
+	assert.Equal(t, []byte(`hello`), st.Secret)
+	assert.Equal(t, `10.0.0.0/8`, st.Network.String())
+	assert.Equal(t, 3*time.Second, st.Retry)
+	assert.Empty(t, md.Undecoded())
+}
+
+func TestDecoder_hookInterface(t *testing.T) {
+
+	type names []string
+
+	dec := NewDecoder(strings.NewReader(`names = ["a", 1, true]`))
+	dec.RegisterHook(reflect.TypeOf((*interface{})(nil)).Elem(), reflect.TypeOf(names{}), func(value interface{}) (interface{}, error) {
+		var res []string
+		for _, item := range value.([]interface{}) {
+			res = append(res, fmt.Sprint(item))
+		}
+		return res, nil
+	})
+
+	var st struct{ Names names }
+	_, err := dec.Decode(&st)
+	require.NoError(t, err)
+	assert.Equal(t, names{`a`, `1`, `true`}, st.Names)
+}
+
+func TestDecoder_hookError(t *testing.T) {
+
+	var st struct{ Timeout time.Duration }
+
+	dec := NewDecoder(strings.NewReader(`
+
+timeout = "soon"`))
+	dec.UseHooks(DurationHook)
+
+	_, err := dec.Decode(&st)
+	require.Error(t, err)
+	assert.Equal(t, `toml: timeout (line 3, col 11): time: invalid duration "soon"`, err.Error())
+}
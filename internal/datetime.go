@@ -0,0 +1,109 @@
+package toml
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// normalizeDateTime rewrites the date-time fragment of type
+// typ as set by opts.
+func normalizeDateTime(typ string, fragment []byte, opts Options) []byte {
+
+	s := strings.Trim(string(fragment), `"`)
+	if typ == `date-local` {
+		return fragment
+	}
+
+	var date, sep, clock string
+	if typ == `time-local` {
+		clock = s
+	} else {
+		date, sep, clock = s[:10], s[10:11], s[11:]
+	}
+
+	hms, rest := clock[:8], clock[8:]
+
+	var frac string
+	if strings.HasPrefix(rest, `.`) {
+		end := 1
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+		frac, rest = rest[1:end], rest[end:]
+	}
+	offset := rest
+
+	if opts.FixedFrac {
+		frac = fixFrac(frac, opts.FracDigits)
+	}
+
+	if opts.DateTimeT && sep != `` {
+		sep = `T`
+	}
+
+	if typ == `datetime` && (opts.DateTimeUTC || opts.DateTimeEpoch && !opts.Tagged) {
+
+		t, err := time.Parse(`2006-01-02T15:04:05Z07:00`, date+`T`+hms+offset)
+		if err != nil {
+			return fragment
+		}
+		t = t.UTC()
+
+		if opts.DateTimeEpoch && !opts.Tagged {
+			return []byte(epoch(t.Unix(), frac))
+		}
+
+		date, hms, offset = t.Format(`2006-01-02`), t.Format(`15:04:05`), `Z`
+	}
+
+	res := `"` + date + sep + hms
+	if frac != `` {
+		res += `.` + frac
+	}
+	return []byte(res + offset + `"`)
+}
+
+// fixFrac pads or truncates the digits of frac to n.
+func fixFrac(frac string, n int) string {
+
+	if len(frac) >= n {
+		return frac[:n]
+	}
+	return frac + strings.Repeat(`0`, n-len(frac))
+}
+
+// epoch writes sec seconds and the fraction digits frac as
+// JSON number.
+func epoch(sec int64, frac string) string {
+
+	if strings.Trim(frac, `0`) == `` {
+		if frac == `` {
+			return strconv.FormatInt(sec, 10)
+		}
+		return strconv.FormatInt(sec, 10) + `.` + frac
+	}
+
+	if sec >= 0 {
+		return strconv.FormatInt(sec, 10) + `.` + frac
+	}
+
+	// -1 + .25 is written -0.75
+	return `-` + strconv.FormatInt(-(sec+1), 10) + `.` + complementFrac(frac)
+}
+
+// complementFrac returns the digits of 1 - 0.frac.
+func complementFrac(frac string) string {
+
+	res := []byte(frac)
+	last := len(res) - 1
+	for last >= 0 && res[last] == '0' {
+		last--
+	}
+
+	for i := 0; i < last; i++ {
+		res[i] = '9' - res[i] + '0'
+	}
+	res[last] = '9' - res[last] + '1'
+	return string(res)
+}
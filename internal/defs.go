@@ -20,12 +20,13 @@ type Defs struct {
 	m             Map
 	arrayKeyStack *ArrayKeyStack
 	keyFilter     *KeyFilter
+	closeFunc     func(key []string)
 }
 
-func MakeDefs() Defs {
+func MakeDefs(indent *indenter) Defs {
 	return Defs{m: Map{m: make(map[string]Map)},
 		arrayKeyStack: &ArrayKeyStack{},
-		keyFilter:     &KeyFilter{},
+		keyFilter:     &KeyFilter{indent: indent},
 	}
 }
 
@@ -50,7 +51,26 @@ func (d Defs) Define(key []string, insertTable []string, v Var) bool {
 		if !ok {
 			return false
 		}
+
+		if d.closeFunc != nil {
+			d.closeFunc(key)
+		}
 	}
 
 	return true
 }
+
+// Close closes the array tables still open at the
+// end of the document.
+func (d Defs) Close() {
+
+	if d.closeFunc == nil {
+		return
+	}
+
+	stack := d.arrayKeyStack.stack
+	for i := len(stack) - 1; i >= 0; i-- {
+		d.closeFunc(strings.Split(stack[i], "\n"))
+	}
+	d.arrayKeyStack.stack = nil
+}
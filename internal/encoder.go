@@ -22,24 +22,22 @@ func BaseKeyFilterPushFunc(baseKey []string, keyFilterFunc KeyFilterPushFunc) Ke
 type KeyFilter struct {
 	path        []segment
 	notBaseHead bool
+	indent      *indenter
 }
 
 func (k KeyFilter) closeSegments(beq int, w io.StringWriter) {
 
 	for i := len(k.path) - 1; i >= beq; i-- {
 
+		k.indent.close(w, "}")
 		if k.path[i].V == ArrayVar {
-			w.WriteString("}]")
-			continue
+			k.indent.close(w, "]")
 		}
-		w.WriteString("}")
 	}
 }
 
 func (k KeyFilter) renderKey(key string, w io.StringWriter) {
-	w.WriteString(`"`)
-	w.WriteString(key)
-	w.WriteString(`":`)
+	k.indent.key(w, key)
 }
 
 func (k *KeyFilter) Push(key []string, v Var, w io.StringWriter) {
@@ -79,7 +77,10 @@ func (k *KeyFilter) Push(key []string, v Var, w io.StringWriter) {
 	}
 
 	if v == ArrayVar && idx == len(k.path) && idx == len(key) {
-		w.WriteString("},{")
+		k.indent.close(w, "}")
+		w.WriteString(",")
+		k.indent.member(w)
+		k.indent.open(w, "{")
 		k.path[len(k.path)-1].Head = true
 		return
 	}
@@ -105,10 +106,10 @@ func (k *KeyFilter) Push(key []string, v Var, w io.StringWriter) {
 
 		k.renderKey(k.path[i].S, w)
 		if k.path[i].V == ArrayVar {
-			w.WriteString("[{")
-		} else {
-			w.WriteString("{")
+			k.indent.open(w, "[")
+			k.indent.member(w)
 		}
+		k.indent.open(w, "{")
 	}
 
 	for i := 0; i < idx; i++ {
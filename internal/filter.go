@@ -184,6 +184,38 @@ var (
 	daysInMonth = []int{31 /*jan*/, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
 )
 
+// escapesOptions reports whether strings are escaped as set by
+// the options. The default path calls the inlined toJSONString,
+// which does not allocate.
+func (s *State) escapesOptions() bool {
+	return s.opts.KeepSlash || s.opts.EscapeHTML
+}
+
+// escapeOption returns r as part of a JSON string, escaped as
+// set by the options.
+func (s *State) escapeOption(r rune) string {
+
+	if r == '/' && s.opts.KeepSlash {
+		return `/`
+	}
+
+	if s.opts.EscapeHTML {
+		switch r {
+		case '<':
+			return `\u003c`
+		case '>':
+			return `\u003e`
+		case '&':
+			return `\u0026`
+		case '\u2028':
+			return `\u2028`
+		case '\u2029':
+			return `\u2029`
+		}
+	}
+	return toJSONString(r)
+}
+
 func toJSONString(r rune) string {
 
 	if r == '"' {
@@ -296,13 +328,19 @@ type Filter struct {
 	Buf   *bytes.Buffer
 }
 
-func NewFilter() *Filter {
+func NewFilter(opts ...Option) *Filter {
 
 	state := State{
-		Buf:  bytes.NewBufferString(`{`),
-		defs: MakeDefs(),
+		Buf: bytes.NewBufferString(`{`),
 	}
 
+	for _, opt := range opts {
+		opt(&state.opts)
+	}
+
+	state.indent = newIndenter(state.opts)
+	state.defs = MakeDefs(state.indent)
+
 	state.PushScope(Top, OtherType, nil)
 
 	return &Filter{Buf: &bytes.Buffer{}, State: state}
@@ -313,11 +351,14 @@ func (f *Filter) Write(p []byte) (int, error) {
 	f.Buf.Write(p)
 
 	for {
-		r, _, err := f.Buf.ReadRune()
+		r, size, err := f.Buf.ReadRune()
 		if errors.Is(err, io.EOF) {
 			break
 		}
 
+		f.State.runeOffset = f.State.offset
+		f.State.offset += size
+
 		if r == '\r' {
 			continue
 		}
@@ -343,6 +384,7 @@ func (f *Filter) Write(p []byte) (int, error) {
 		}
 	}
 	f.Buf.Truncate(f.Buf.Len())
+	f.State.runeOffset = f.State.offset
 	return len(p), nil
 }
 
@@ -364,6 +406,10 @@ func (f *Filter) WriteRune(r rune) error {
 			return nil
 		}
 
+		if f.State.valueEnded() {
+			f.State.endValue()
+		}
+
 		scidx, ok := f.State.topScopeIdx()
 		if ok {
 			err := f.State.Scopes[scidx].Parse(r, &f.State)
@@ -380,7 +426,8 @@ func (f *Filter) WriteRune(r rune) error {
 
 func (f *Filter) Close() {
 	f.State.defs.keyFilter.Close(f.State.Buf)
-	f.State.Buf.WriteRune('}')
+	f.State.indent.close(f.State.Buf, "}")
+	f.State.defs.Close()
 }
 
 type Scope struct {
@@ -399,14 +446,24 @@ func (s *Scope) Parse(r rune, state *State) error {
 type ParseFunc func(r rune, state *State, scope *Scope) error
 
 type State struct {
-	Buf       *bytes.Buffer
-	Scopes    []Scope
-	defs      Defs
-	line      int
-	position  int
-	inComment bool
-	keyData   []rune
-	data      []rune
+	Buf        *bytes.Buffer
+	Scopes     []Scope
+	defs       Defs
+	line       int
+	position   int
+	offset     int
+	runeOffset int
+	inComment  bool
+	keyData    []rune
+	data       []rune
+	sink       Sink
+	value      openValue
+	keyPos     Position
+	headerPos  Position
+	opts       Options
+	indent     *indenter
+	out        *bytes.Buffer
+	scalar     bytes.Buffer
 }
 
 func (s *State) PushScope(parse ParseFunc, scopeType ScopeType, thisScope *Scope) {
@@ -605,6 +662,9 @@ func QuotedString(r rune, state *State, scope *Scope) error {
 	}
 	scope.lastToken = OTHERT
 	js := toJSONString(r)
+	if state.escapesOptions() {
+		js = state.escapeOption(r)
+	}
 	if scope.scopeType == KeyType {
 		state.keyData = append(state.keyData, []rune(js)...)
 	} else {
@@ -749,6 +809,10 @@ func TrippleQuotedString(r rune, state *State, scope *Scope) error {
 	}
 
 	scope.lastToken = OTHERT
+	if state.escapesOptions() {
+		state.Buf.WriteString(state.escapeOption(r))
+		return nil
+	}
 	state.Buf.WriteString(toJSONString(r))
 	return nil
 }
@@ -769,6 +833,9 @@ func LiteralString(r rune, state *State, scope *Scope) error {
 	}
 
 	js := toJSONString(r)
+	if state.escapesOptions() {
+		js = state.escapeOption(r)
+	}
 	if scope.scopeType == KeyType {
 		state.keyData = append(state.keyData, []rune(js)...)
 	} else {
@@ -835,6 +902,10 @@ func MultiLineLiteralString(r rune, state *State, scope *Scope) error {
 	}
 
 	scope.lastToken = OTHERT
+	if state.escapesOptions() {
+		state.Buf.WriteString(state.escapeOption(r))
+		return nil
+	}
 	state.Buf.WriteString(toJSONString(r))
 	return nil
 }
@@ -1275,9 +1346,9 @@ func inlineTableDispatchKeyValue(r rune, defs Defs, state *State, scope *Scope)
 	return parseError(state, `inline table could not dispatch key`)
 }
 
-func InlineTable() ParseFunc {
+func InlineTable(indent *indenter) ParseFunc {
 
-	defs := MakeDefs()
+	defs := MakeDefs(indent)
 	return func(r rune, state *State, scope *Scope) error {
 
 		if r == '\n' {
@@ -1297,7 +1368,8 @@ func InlineTable() ParseFunc {
 			}
 
 			defs.keyFilter.Close(state.Buf)
-			state.Buf.WriteRune('}')
+			state.indent.close(state.Buf, "}")
+			state.close(InlineTableKind)
 			return nil
 		}
 
@@ -1325,7 +1397,8 @@ func InlineArray(r rune, state *State, scope *Scope) error {
 
 	if r == ']' {
 		state.PopScope()
-		state.Buf.WriteRune(']')
+		state.indent.close(state.Buf, "]")
+		state.close(ArrayKind)
 		return nil
 	}
 
@@ -1341,6 +1414,7 @@ func InlineArray(r rune, state *State, scope *Scope) error {
 		}
 		state.Buf.WriteRune(',')
 	}
+	state.indent.member(state.Buf)
 	scope.lastToken = OTHERT
 	scope.state = AfterValueState
 	state.PushScope(Value, OtherType, scope)
@@ -1384,6 +1458,7 @@ func DateOrTime(hasZeroPrefix bool) ParseFunc {
 
 			state.Buf.WriteString(`"`)
 			scope.state = AfterValueState
+			state.setValueKind(DateTimeKind)
 			state.PushScope(Time(2, state.data, false), OtherType, scope)
 			return ErrDontAdvance
 		}
@@ -1392,6 +1467,7 @@ func DateOrTime(hasZeroPrefix bool) ParseFunc {
 
 			state.Buf.WriteString(`"`)
 			scope.state = AfterValueState
+			state.setValueKind(DateTimeKind)
 			state.PushScope(Date(4, state.data), OtherType, scope)
 			return ErrDontAdvance
 		}
@@ -1421,11 +1497,10 @@ func SignedNumber(r rune, state *State, scope *Scope) error {
 
 	if r == 'n' {
 
-		state.Buf.WriteRune('"')
-		if len(state.data) == 1 {
-			state.Buf.WriteRune(state.data[0])
+		err := writeSpecial(state, `nan`)
+		if err != nil {
+			return err
 		}
-		state.Buf.WriteString(`nan"`)
 
 		state.PopScope()
 		state.PushScope(LiteralValue(nanRunes), OtherType, nil)
@@ -1434,11 +1509,10 @@ func SignedNumber(r rune, state *State, scope *Scope) error {
 
 	if r == 'i' {
 
-		state.Buf.WriteRune('"')
-		if len(state.data) == 1 {
-			state.Buf.WriteRune(state.data[0])
+		err := writeSpecial(state, `inf`)
+		if err != nil {
+			return err
 		}
-		state.Buf.WriteString(`inf"`)
 
 		state.PopScope()
 		state.PushScope(LiteralValue(infRunes), OtherType, nil)
@@ -1458,6 +1532,43 @@ func SignedNumber(r rune, state *State, scope *Scope) error {
 	return ErrDontAdvance
 }
 
+// writeSpecial writes the float inf or nan, signed by the
+// rune in state.data if there is one.
+func writeSpecial(state *State, name string) error {
+
+	state.setValueKind(SpecialKind)
+
+	var sign string
+	if len(state.data) == 1 {
+		sign = string(state.data[0])
+	}
+
+	// tagged values are strings, only the error applies
+	policy := state.opts.InfNaN
+	if state.opts.Tagged && policy != InfNaNError {
+		policy = InfNaNString
+	}
+
+	switch policy {
+	case InfNaNNull:
+		state.Buf.WriteString(`null`)
+	case InfNaNError:
+		return parseError(state, `float `+sign+name+` not allowed`)
+	case InfNaNJSON5:
+		if name == `nan` {
+			state.Buf.WriteString(`NaN`)
+			break
+		}
+		if sign == `-` {
+			state.Buf.WriteRune('-')
+		}
+		state.Buf.WriteString(`Infinity`)
+	default:
+		state.Buf.WriteString(`"` + sign + name + `"`)
+	}
+	return nil
+}
+
 func NumberDateOrTime(r rune, state *State, scope *Scope) error {
 
 	if scope.state == AfterValueState {
@@ -1496,6 +1607,7 @@ func NumberDateOrTime(r rune, state *State, scope *Scope) error {
 
 		state.Buf.WriteString(`"`)
 		scope.state = AfterValueState
+		state.setValueKind(DateTimeKind)
 		state.PushScope(Time(2, state.data, false), OtherType, scope)
 		return ErrDontAdvance
 	}
@@ -1504,6 +1616,7 @@ func NumberDateOrTime(r rune, state *State, scope *Scope) error {
 
 		scope.state = AfterValueState
 		state.Buf.WriteString(`"`)
+		state.setValueKind(DateTimeKind)
 		state.PushScope(Date(4, state.data), OtherType, scope)
 		return ErrDontAdvance
 	}
@@ -1571,6 +1684,7 @@ func Zero(r rune, state *State, scope *Scope) error {
 func Value(r rune, state *State, scope *Scope) error {
 
 	if scope.lastToken == OTHERT && r == '"' {
+		state.beginValue(StringKind)
 		scope.lastToken = QT
 		scope.scopeType = StringType
 		return nil
@@ -1602,6 +1716,7 @@ func Value(r rune, state *State, scope *Scope) error {
 	}
 
 	if scope.lastToken == OTHERT && r == '\'' {
+		state.beginValue(StringKind)
 		scope.lastToken = SQT
 		scope.scopeType = StringType
 		return nil
@@ -1637,6 +1752,7 @@ func Value(r rune, state *State, scope *Scope) error {
 	}
 
 	if r == 't' {
+		state.beginValue(BoolKind)
 		state.PopScope()
 		state.PushScope(LiteralValue(trueRunes), OtherType, nil)
 		state.Buf.WriteString(`true`)
@@ -1644,6 +1760,7 @@ func Value(r rune, state *State, scope *Scope) error {
 	}
 
 	if r == 'f' {
+		state.beginValue(BoolKind)
 		state.PopScope()
 		state.PushScope(LiteralValue(falseRunes), OtherType, nil)
 		state.Buf.WriteString(`false`)
@@ -1651,25 +1768,29 @@ func Value(r rune, state *State, scope *Scope) error {
 	}
 
 	if r == '{' {
+		state.open(InlineTableKind)
 		state.PopScope()
-		state.PushScope(InlineTable(), OtherType, nil)
-		state.Buf.WriteRune('{')
+		state.PushScope(InlineTable(state.indent), OtherType, nil)
+		state.indent.open(state.Buf, "{")
 		return nil
 	}
 
 	if r == '[' {
+		state.open(ArrayKind)
 		state.PopScope()
 		state.PushScope(InlineArray, OtherType, nil)
-		state.Buf.WriteRune('[')
+		state.indent.open(state.Buf, "[")
 		return nil
 	}
 
 	if r == '0' {
+		state.beginValue(NumberKind)
 		state.PopScope()
 		state.PushScope(Zero, OtherType, nil)
 		return nil
 	}
 
+	state.beginValue(NumberKind)
 	state.PopScope()
 	state.PushScope(NumberDateOrTime, OtherType, nil)
 	return ErrDontAdvance
@@ -1700,6 +1821,7 @@ func Key(r rune, state *State, scope *Scope) error {
 	st := scope.state
 	if scope.state == OtherState {
 		scope.state = InitState
+		state.keyPos = state.Pos()
 	}
 
 	if scope.state == AfterQuoteState {
@@ -1786,8 +1908,9 @@ func KeyValue(defineFunc DefineFunc, pushFilter KeyFilterPushFunc) ParseFunc {
 				scope.state = AfterValueState
 
 				pushFilter(scope.key, BasicVar, state.Buf)
+				state.pushKey(scope.key)
 
-				state.Buf.WriteString(`"` + scope.key[len(scope.key)-1] + `":`)
+				state.indent.key(state.Buf, scope.key[len(scope.key)-1])
 
 				state.PushScope(Value, OtherType, scope)
 				return nil
@@ -1852,6 +1975,7 @@ func Table(r rune, state *State, scope *Scope) error {
 			return parseError(state, `table attempt to redefine a key`)
 		}
 		state.defs.keyFilter.Push(scope.key, TableVar, state.Buf)
+		state.pushTable(scope.key, TableVar)
 
 		scope.state = AfterTableState
 		return nil
@@ -1925,6 +2049,7 @@ func Array(r rune, state *State, scope *Scope) error {
 			return parseError(state, `array attempt to redefine a key`)
 		}
 		state.defs.keyFilter.Push(scope.key, ArrayVar, state.Buf)
+		state.pushTable(scope.key, ArrayVar)
 
 		scope.state = AfterArrayState
 		return nil
@@ -2018,6 +2143,7 @@ func Top(r rune, state *State, scope *Scope) error {
 
 	if r == '[' {
 		scope.lastToken = CBT
+		state.headerPos = state.Pos()
 		return nil
 	}
 
@@ -2047,3 +2173,18 @@ func isOneOf(r rune, runes []rune) bool {
 	}
 	return false
 }
+
+// IsBare reports whether key can be written as a bare key.
+func IsBare(key string) bool {
+
+	if key == `` {
+		return false
+	}
+
+	for _, r := range key {
+		if !unicode.IsOneOf(bareRanges, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -1231,3 +1231,11 @@ xx"""`,
 		assert.Equal(t, ts.expected, string(f.State.Buf.Bytes()))
 	}
 }
+
+func TestIsBare(t *testing.T) {
+
+	assert.True(t, IsBare(`bare-key_1`))
+	assert.False(t, IsBare(``))
+	assert.False(t, IsBare(`a.b`))
+	assert.False(t, IsBare(`ʎǝʞ`))
+}
@@ -0,0 +1,84 @@
+package toml
+
+import (
+	"io"
+	"strings"
+)
+
+// indenter writes the structure of the JSON, putting every
+// member of objects and arrays on a line of its own like
+// json.Indent does. A nil indenter writes compact JSON.
+type indenter struct {
+	prefix string
+	indent string
+
+	// members holds for every open object and array
+	// whether a member was written to it.
+	members []bool
+}
+
+func newIndenter(opts Options) *indenter {
+
+	if !opts.Indented {
+		return nil
+	}
+
+	// the document object is opened by NewFilter
+	return &indenter{prefix: opts.Prefix, indent: opts.Indent, members: []bool{false}}
+}
+
+// open writes the opening s of an object or array.
+func (in *indenter) open(w io.StringWriter, s string) {
+
+	w.WriteString(s)
+	if in != nil {
+		in.members = append(in.members, false)
+	}
+}
+
+// close writes the closing s of the innermost object or array.
+// Empty ones stay on one line.
+func (in *indenter) close(w io.StringWriter, s string) {
+
+	if in != nil && len(in.members) > 0 {
+		last := len(in.members) - 1
+		hasMembers := in.members[last]
+		in.members = in.members[:last]
+		if hasMembers {
+			in.newline(w)
+		}
+	}
+	w.WriteString(s)
+}
+
+// member starts a member of the innermost object or array,
+// after the comma separating it from the previous one.
+func (in *indenter) member(w io.StringWriter) {
+
+	if in == nil || len(in.members) == 0 {
+		return
+	}
+
+	in.members[len(in.members)-1] = true
+	in.newline(w)
+}
+
+// key starts the member key of the innermost object.
+func (in *indenter) key(w io.StringWriter, key string) {
+
+	in.member(w)
+	w.WriteString(`"`)
+	w.WriteString(key)
+	if in == nil {
+		w.WriteString(`":`)
+		return
+	}
+	w.WriteString(`": `)
+}
+
+func (in *indenter) newline(w io.StringWriter) {
+
+	w.WriteString("\n")
+	w.WriteString(in.prefix)
+	w.WriteString(strings.Repeat(in.indent, len(in.members)))
+}
@@ -0,0 +1,73 @@
+package toml
+
+// Options change the JSON the filter writes. The zero value
+// writes the default output.
+type Options struct {
+	// KeepSlash writes / as is instead of \/.
+	KeepSlash bool
+
+	// EscapeHTML writes <, >, &, U+2028 and U+2029 as \u
+	// escapes, like encoding/json does, so the JSON can be
+	// embedded in HTML.
+	EscapeHTML bool
+
+	// Tagged writes every scalar as an object holding its
+	// TOML type and its value as string, like the JSON of
+	// toml-test: {"type":"date-local","value":"1979-05-27"}.
+	Tagged bool
+
+	// Indented puts every member of objects and arrays on a
+	// line of its own, starting with Prefix followed by one
+	// Indent per nesting level, like json.Indent.
+	Indented bool
+	Prefix   string
+	Indent   string
+
+	// InfNaN applies to tagged scalars only with InfNaNError,
+	// otherwise they hold the TOML spelling.
+	InfNaN InfNaN
+
+	// DateTimeT writes T between the date and the time of
+	// date-times written with a space.
+	DateTimeT bool
+
+	// DateTimeUTC converts offset date-times to UTC.
+	DateTimeUTC bool
+
+	// FixedFrac pads or truncates the fractional seconds of
+	// date-times and times to FracDigits digits.
+	FixedFrac  bool
+	FracDigits int
+
+	// DateTimeEpoch writes offset date-times as the number of
+	// seconds since the Unix epoch. It is ignored for tagged
+	// scalars, whose values are strings.
+	DateTimeEpoch bool
+}
+
+func (o Options) normalizesDateTime() bool {
+	return o.DateTimeT || o.DateTimeUTC || o.FixedFrac || o.DateTimeEpoch
+}
+
+// InfNaN selects how the floats inf and nan are written.
+type InfNaN int
+
+const (
+	// InfNaNString writes them as the strings "inf", "+inf",
+	// "-inf", "nan", "+nan" and "-nan", as spelled in the
+	// document.
+	InfNaNString InfNaN = iota
+
+	// InfNaNNull writes them as null.
+	InfNaNNull
+
+	// InfNaNError fails the parse.
+	InfNaNError
+
+	// InfNaNJSON5 writes them as Infinity, -Infinity and NaN,
+	// as JSON5 and JavaScript do.
+	InfNaNJSON5
+)
+
+// Option sets one of the Options.
+type Option func(o *Options)
@@ -0,0 +1,132 @@
+package toml
+
+type ValueKind string
+
+var (
+	StringKind      ValueKind = `string`
+	BoolKind        ValueKind = `bool`
+	NumberKind      ValueKind = `number`
+	SpecialKind     ValueKind = `special` // inf and nan
+	DateTimeKind    ValueKind = `date-time`
+	InlineTableKind ValueKind = `inline-table`
+	ArrayKind       ValueKind = `array`
+)
+
+// Position points to a rune of the parsed document.
+// Line and Col start at 1, Offset is the byte offset
+// from the start of the document.
+type Position struct {
+	Line   int
+	Col    int
+	Offset int
+}
+
+// Sink receives the structure of a document while the
+// filter parses it. Scalars are handed over as the JSON
+// fragment the filter rendered for them.
+type Sink interface {
+	// Table is called for [table] (TableVar) and
+	// [[array]] (ArrayVar) headers. start points to the
+	// opening bracket, end to the end of the header line.
+	Table(key []string, v Var, start Position, end Position)
+
+	// Key is called for the key of a key/value pair. The key
+	// is relative to the current table or inline table.
+	Key(key []string, pos Position)
+
+	// Open and Close enclose inline tables and arrays.
+	Open(kind ValueKind, pos Position)
+	Close(kind ValueKind, pos Position)
+
+	Value(kind ValueKind, fragment []byte, start Position, end Position)
+
+	// CloseArrayTable is called when no more keys can be
+	// added to the last element of the array of tables at key.
+	CloseArrayTable(key []string)
+}
+
+type openValue struct {
+	open  bool
+	kind  ValueKind
+	depth int
+	pos   Position
+}
+
+// SetSink makes the filter report to sink. The JSON stream
+// written to State.Buf is only kept up to the next value
+// then, so it should not be read by anyone else.
+func (f *Filter) SetSink(sink Sink) {
+	f.State.sink = sink
+	f.State.defs.closeFunc = sink.CloseArrayTable
+}
+
+func (s *State) Pos() Position {
+	return Position{Line: s.line + 1, Col: s.position, Offset: s.runeOffset}
+}
+
+func (s *State) beginValue(kind ValueKind) {
+
+	if s.sink == nil {
+		if s.opts.Tagged || s.opts.normalizesDateTime() {
+			s.beginScalar(kind)
+		}
+		return
+	}
+
+	s.Buf.Reset()
+	s.value = openValue{
+		open:  true,
+		kind:  kind,
+		depth: len(s.Scopes),
+		pos:   s.Pos(),
+	}
+}
+
+func (s *State) setValueKind(kind ValueKind) {
+	s.value.kind = kind
+}
+
+func (s *State) endValue() {
+
+	s.value.open = false
+
+	if s.sink == nil {
+		s.endScalar()
+		return
+	}
+
+	fragment := make([]byte, s.Buf.Len())
+	copy(fragment, s.Buf.Bytes())
+	s.Buf.Reset()
+
+	s.sink.Value(s.value.kind, fragment, s.value.pos, s.Pos())
+}
+
+func (s *State) valueEnded() bool {
+	return s.value.open && len(s.Scopes) < s.value.depth
+}
+
+func (s *State) pushKey(key []string) {
+	if s.sink != nil {
+		s.sink.Key(key, s.keyPos)
+	}
+}
+
+func (s *State) pushTable(key []string, v Var) {
+	if s.sink != nil {
+		s.sink.Table(key, v, s.headerPos, s.Pos())
+	}
+}
+
+func (s *State) open(kind ValueKind) {
+	if s.sink != nil {
+		s.Buf.Reset()
+		s.sink.Open(kind, s.Pos())
+	}
+}
+
+func (s *State) close(kind ValueKind) {
+	if s.sink != nil {
+		s.sink.Close(kind, s.Pos())
+	}
+}
@@ -0,0 +1,87 @@
+package toml
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordSink struct {
+	events []string
+}
+
+func (r *recordSink) Table(key []string, v Var, start Position, end Position) {
+	r.events = append(r.events, fmt.Sprintf(`table %v %v %v:%v %v`, strings.Join(key, `.`), v, start.Line, start.Col, end.Offset-start.Offset))
+}
+
+func (r *recordSink) Key(key []string, pos Position) {
+	r.events = append(r.events, fmt.Sprintf(`key %v %v:%v`, strings.Join(key, `.`), pos.Line, pos.Col))
+}
+
+func (r *recordSink) Open(kind ValueKind, pos Position) {
+	r.events = append(r.events, fmt.Sprintf(`open %v`, kind))
+}
+
+func (r *recordSink) Close(kind ValueKind, pos Position) {
+	r.events = append(r.events, fmt.Sprintf(`close %v`, kind))
+}
+
+func (r *recordSink) Value(kind ValueKind, fragment []byte, start Position, end Position) {
+	r.events = append(r.events, fmt.Sprintf(`value %v %s %v:%v %v`, kind, fragment, start.Line, start.Col, end.Offset-start.Offset))
+}
+
+func (r *recordSink) CloseArrayTable(key []string) {
+	r.events = append(r.events, fmt.Sprintf(`close-array-table %v`, strings.Join(key, `.`)))
+}
+
+func TestSink(t *testing.T) {
+
+	doc := `a = "x"
+[t]
+b.c = [1, 2.5, {d = 1979-05-27}]
+[[arr]]
+e = -inf
+f = true
+[[arr]]
+[[arr.sub]]
+[x]`
+
+	sink := &recordSink{}
+	filter := NewFilter()
+	filter.SetSink(sink)
+
+	_, err := filter.Write([]byte(doc))
+	require.NoError(t, err)
+	require.NoError(t, filter.WriteRune('\n'))
+	require.NoError(t, filter.WriteRune(EOF))
+	filter.Close()
+
+	assert.Equal(t, []string{
+		`key a 1:1`,
+		`value string "x" 1:5 3`,
+		`table t table-var 2:1 3`,
+		`key b.c 3:1`,
+		`open array`,
+		`value number 1 3:8 1`,
+		`value number 2.5 3:11 3`,
+		`open inline-table`,
+		`key d 3:17`,
+		`value date-time "1979-05-27" 3:21 10`,
+		`close inline-table`,
+		`close array`,
+		`table arr array-var 4:1 7`,
+		`key e 5:1`,
+		`value special "-inf" 5:5 4`,
+		`key f 6:1`,
+		`value bool true 6:5 4`,
+		`close-array-table arr`,
+		`table arr array-var 7:1 7`,
+		`table arr.sub array-var 8:1 11`,
+		`close-array-table arr.sub`,
+		`close-array-table arr`,
+		`table x table-var 9:1 3`,
+	}, sink.events)
+}
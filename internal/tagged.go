@@ -0,0 +1,126 @@
+package toml
+
+import (
+	"bytes"
+	"strings"
+)
+
+// beginScalar starts a scalar that is tagged or, being a
+// date-time, normalized. Strings can be long and their type is
+// known, so they are written while parsed. The other scalars
+// are written to State.scalar until it is known which TOML type
+// they have.
+func (s *State) beginScalar(kind ValueKind) {
+
+	s.value = openValue{
+		open:  true,
+		kind:  kind,
+		depth: len(s.Scopes),
+		pos:   s.Pos(),
+	}
+
+	if kind == StringKind {
+		if s.opts.Tagged {
+			s.beginTag(`string`)
+		}
+		return
+	}
+
+	s.scalar.Reset()
+	s.out = s.Buf
+	s.Buf = &s.scalar
+}
+
+func (s *State) endScalar() {
+
+	if s.value.kind == StringKind {
+		if s.opts.Tagged {
+			s.indent.close(s.Buf, "}")
+		}
+		return
+	}
+
+	fragment := s.Buf.Bytes()
+	s.Buf = s.out
+	s.out = nil
+
+	typ := ScalarType(s.value.kind, fragment)
+	if s.value.kind == DateTimeKind {
+		fragment = normalizeDateTime(typ, fragment, s.opts)
+	}
+
+	if !s.opts.Tagged {
+		s.Buf.Write(fragment)
+		return
+	}
+
+	s.beginTag(typ)
+	if len(fragment) > 0 && fragment[0] == '"' {
+		s.Buf.Write(fragment)
+	} else {
+		s.Buf.WriteRune('"')
+		s.Buf.Write(fragment)
+		s.Buf.WriteRune('"')
+	}
+	s.indent.close(s.Buf, "}")
+}
+
+// beginTag writes the tag object up to its value.
+func (s *State) beginTag(typ string) {
+	s.indent.open(s.Buf, "{")
+	s.indent.key(s.Buf, `type`)
+	s.Buf.WriteString(`"` + typ + `",`)
+	s.indent.key(s.Buf, `value`)
+}
+
+// Output returns the buffer the JSON is written to. It is
+// State.Buf unless a scalar is held back in State.scalar.
+func (s *State) Output() *bytes.Buffer {
+	if s.out != nil {
+		return s.out
+	}
+	return s.Buf
+}
+
+// ScalarType returns the TOML type of the scalar of kind the
+// filter rendered as fragment, named like in toml-test:
+// string, integer, float, bool, datetime, datetime-local,
+// date-local or time-local.
+func ScalarType(kind ValueKind, fragment []byte) string {
+
+	switch kind {
+	case StringKind:
+		return `string`
+	case BoolKind:
+		return `bool`
+	case SpecialKind:
+		return `float`
+	case DateTimeKind:
+		return dateTimeType(strings.Trim(string(fragment), `"`))
+	}
+
+	if bytes.ContainsAny(fragment, `.eE`) {
+		return `float`
+	}
+	return `integer`
+}
+
+func dateTimeType(s string) string {
+
+	if len(s) > 2 && s[2] == ':' {
+		return `time-local`
+	}
+
+	if len(s) == 10 {
+		return `date-local`
+	}
+
+	if strings.HasSuffix(s, `Z`) || strings.HasSuffix(s, `z`) {
+		return `datetime`
+	}
+
+	if idx := strings.LastIndexAny(s, `+-`); idx > 10 {
+		return `datetime`
+	}
+	return `datetime-local`
+}
@@ -0,0 +1,183 @@
+package toml
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	toml "github.com/komkom/toml/internal"
+)
+
+const (
+	localDateLayout     = `2006-01-02`
+	localTimeLayout     = `15:04:05`
+	localDateTimeLayout = `2006-01-02T15:04:05`
+)
+
+// LocalDate is a TOML local date, a date without
+// time of day and offset.
+type LocalDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// LocalDateOf returns the date of t.
+func LocalDateOf(t time.Time) LocalDate {
+	y, m, d := t.Date()
+	return LocalDate{Year: y, Month: m, Day: d}
+}
+
+// ParseLocalDate parses a date of the form 1979-05-27.
+func ParseLocalDate(s string) (LocalDate, error) {
+	t, err := time.Parse(localDateLayout, s)
+	if err != nil {
+		return LocalDate{}, err
+	}
+	return LocalDateOf(t), nil
+}
+
+func (d LocalDate) String() string {
+	return fmt.Sprintf(`%04d-%02d-%02d`, d.Year, d.Month, d.Day)
+}
+
+// In returns the start of the date in loc.
+func (d LocalDate) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+func (d LocalDate) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *LocalDate) UnmarshalText(data []byte) error {
+	var err error
+	*d, err = ParseLocalDate(string(data))
+	return err
+}
+
+// LocalTime is a TOML local time, a time of day without
+// date and offset.
+type LocalTime struct {
+	Hour       int
+	Minute     int
+	Second     int
+	Nanosecond int
+}
+
+// LocalTimeOf returns the time of day of t.
+func LocalTimeOf(t time.Time) LocalTime {
+	return LocalTime{Hour: t.Hour(), Minute: t.Minute(), Second: t.Second(), Nanosecond: t.Nanosecond()}
+}
+
+// ParseLocalTime parses a time of the form 07:32:00
+// with optional fractional seconds.
+func ParseLocalTime(s string) (LocalTime, error) {
+	t, err := time.Parse(localTimeLayout, s)
+	if err != nil {
+		return LocalTime{}, err
+	}
+	return LocalTimeOf(t), nil
+}
+
+func (t LocalTime) String() string {
+
+	s := fmt.Sprintf(`%02d:%02d:%02d`, t.Hour, t.Minute, t.Second)
+	if t.Nanosecond == 0 {
+		return s
+	}
+	return s + strings.TrimRight(fmt.Sprintf(`.%09d`, t.Nanosecond), `0`)
+}
+
+func (t LocalTime) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *LocalTime) UnmarshalText(data []byte) error {
+	var err error
+	*t, err = ParseLocalTime(string(data))
+	return err
+}
+
+// LocalDateTime is a TOML local date-time, a date and
+// time of day without offset.
+type LocalDateTime struct {
+	Date LocalDate
+	Time LocalTime
+}
+
+// LocalDateTimeOf returns the date and time of day of t.
+func LocalDateTimeOf(t time.Time) LocalDateTime {
+	return LocalDateTime{Date: LocalDateOf(t), Time: LocalTimeOf(t)}
+}
+
+// ParseLocalDateTime parses a date-time of the form
+// 1979-05-27T07:32:00 with optional fractional seconds.
+// A space may separate date and time.
+func ParseLocalDateTime(s string) (LocalDateTime, error) {
+	t, err := time.Parse(localDateTimeLayout, replaceSeparator(s))
+	if err != nil {
+		return LocalDateTime{}, err
+	}
+	return LocalDateTimeOf(t), nil
+}
+
+func (dt LocalDateTime) String() string {
+	return dt.Date.String() + `T` + dt.Time.String()
+}
+
+// In returns the date-time in loc.
+func (dt LocalDateTime) In(loc *time.Location) time.Time {
+	return time.Date(dt.Date.Year, dt.Date.Month, dt.Date.Day,
+		dt.Time.Hour, dt.Time.Minute, dt.Time.Second, dt.Time.Nanosecond, loc)
+}
+
+func (dt LocalDateTime) MarshalText() ([]byte, error) {
+	return []byte(dt.String()), nil
+}
+
+func (dt *LocalDateTime) UnmarshalText(data []byte) error {
+	var err error
+	*dt, err = ParseLocalDateTime(string(data))
+	return err
+}
+
+func replaceSeparator(s string) string {
+	if len(s) > 10 && s[10] == ' ' {
+		return s[:10] + `T` + s[11:]
+	}
+	return s
+}
+
+// parseDateTime parses the date-time s, returning a
+// time.Time for offset date-times and a local type
+// otherwise.
+func parseDateTime(s string) (Type, interface{}, error) {
+
+	typ := Type(toml.ScalarType(toml.DateTimeKind, []byte(s)))
+
+	var v interface{}
+	var err error
+	switch typ {
+	case DateTimeType:
+		v, err = time.Parse(time.RFC3339, replaceSeparator(s))
+	case LocalDateTimeType:
+		v, err = ParseLocalDateTime(s)
+	case LocalDateType:
+		v, err = ParseLocalDate(s)
+	case LocalTimeType:
+		v, err = ParseLocalTime(s)
+	}
+
+	if err != nil {
+		return InvalidType, nil, fmt.Errorf(`invalid date-time %v`, s)
+	}
+	return typ, v, nil
+}
+
+func formatDateTime(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,73 @@
+package toml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDateTime(t *testing.T) {
+
+	tests := []struct {
+		value    string
+		typ      Type
+		expected string
+		err      string
+	}{
+		{
+			value:    `1979-05-27T07:32:00Z`,
+			typ:      DateTimeType,
+			expected: `1979-05-27T07:32:00Z`,
+		},
+		{
+			value:    `1979-05-27 07:32:00.5+01:30`,
+			typ:      DateTimeType,
+			expected: `1979-05-27T07:32:00.5+01:30`,
+		},
+		{
+			value:    `1979-05-27 07:32:00.123456789123`,
+			typ:      LocalDateTimeType,
+			expected: `1979-05-27T07:32:00.123456789`,
+		},
+		{
+			value:    `1979-05-27`,
+			typ:      LocalDateType,
+			expected: `1979-05-27`,
+		},
+		{
+			value:    `07:32:00.100`,
+			typ:      LocalTimeType,
+			expected: `07:32:00.1`,
+		},
+		{
+			value: `1979-02-30`,
+			err:   `invalid date-time`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		typ, v, err := parseDateTime(ts.value)
+		if ts.err != `` {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), ts.err)
+			continue
+		}
+
+		require.NoError(t, err)
+		assert.Equal(t, ts.typ, typ)
+		assert.Equal(t, ts.expected, formatDateTime(v))
+	}
+}
+
+func TestLocalDateTime_text(t *testing.T) {
+
+	var dt LocalDateTime
+	err := dt.UnmarshalText([]byte(`2021-01-02T03:04:05.06`))
+	require.NoError(t, err)
+
+	data, err := dt.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, `2021-01-02T03:04:05.06`, string(data))
+}
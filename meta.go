@@ -0,0 +1,109 @@
+package toml
+
+import (
+	"fmt"
+	"strings"
+
+	toml "github.com/komkom/toml/internal"
+)
+
+// Key is the path of a key in a TOML document.
+type Key []string
+
+// String renders the key as a dotted TOML key, quoting
+// the parts that are not valid bare keys.
+func (k Key) String() string {
+
+	parts := make([]string, len(k))
+	for i, p := range k {
+		parts[i] = p
+		if !toml.IsBare(p) {
+			parts[i] = quoteString(p)
+		}
+	}
+	return strings.Join(parts, `.`)
+}
+
+func (k Key) id() string {
+	return fmt.Sprintf(`%q`, []string(k))
+}
+
+// MetaData describes the keys of a decoded document.
+type MetaData struct {
+	keys      []Key
+	types     map[string]Type
+	undecoded []Key
+}
+
+func newMetaData(root *node) MetaData {
+
+	md := MetaData{types: make(map[string]Type)}
+	undecoded := make(map[string]bool)
+	md.walk(root, nil, undecoded)
+
+	for _, k := range md.keys {
+		if undecoded[k.id()] {
+			md.undecoded = append(md.undecoded, k)
+		}
+	}
+	return md
+}
+
+func (md *MetaData) walk(n *node, key Key, undecoded map[string]bool) {
+
+	for _, k := range n.keys {
+
+		child := n.fields[k]
+
+		ck := make(Key, len(key)+1)
+		copy(ck, key)
+		ck[len(key)] = k
+
+		id := ck.id()
+		if _, ok := md.types[id]; !ok {
+			md.keys = append(md.keys, ck)
+			md.types[id] = child.typ
+		}
+
+		if !child.decoded {
+			undecoded[id] = true
+		}
+
+		if child.typ.isTable() {
+			md.walk(child, ck, undecoded)
+		}
+
+		if child.typ == ArrayOfTablesType {
+			for _, item := range child.items {
+				md.walk(item, ck, undecoded)
+			}
+		}
+	}
+}
+
+// Keys returns all keys of the document in the order
+// they appear. Keys inside arrays of tables are listed
+// once.
+func (md MetaData) Keys() []Key {
+	return md.keys
+}
+
+// IsDefined reports whether the key is defined in
+// the document.
+func (md MetaData) IsDefined(key ...string) bool {
+	_, ok := md.types[Key(key).id()]
+	return ok
+}
+
+// Type returns the TOML type of the value at key, or
+// InvalidType if key is not defined.
+func (md MetaData) Type(key ...string) Type {
+	return md.types[Key(key).id()]
+}
+
+// Undecoded returns the keys of the document that were
+// not decoded into the target value, in the order they
+// appear.
+func (md MetaData) Undecoded() []Key {
+	return md.undecoded
+}
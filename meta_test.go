@@ -0,0 +1,119 @@
+package toml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const metaDoc = `
+title = "x"
+prot = 8080
+point = {x = 1, y = 2}
+
+[server]
+host = "localhost"
+"tls cert" = "a.pem"
+
+[[server.route]]
+path = "/"
+
+[[server.route]]
+path = "/api"
+timeout = 1.5
+`
+
+func TestMetaData(t *testing.T) {
+
+	var cfg struct {
+		Title  string
+		Point  map[string]int
+		Server struct {
+			Host  string
+			Route []struct {
+				Path string
+			}
+		}
+	}
+
+	md, err := NewDecoder(strings.NewReader(metaDoc)).Decode(&cfg)
+	require.NoError(t, err)
+
+	var keys []string
+	for _, k := range md.Keys() {
+		keys = append(keys, k.String())
+	}
+	assert.Equal(t, []string{
+		`title`,
+		`prot`,
+		`point`,
+		`point.x`,
+		`point.y`,
+		`server`,
+		`server.host`,
+		`server."tls cert"`,
+		`server.route`,
+		`server.route.path`,
+		`server.route.timeout`,
+	}, keys)
+
+	assert.True(t, md.IsDefined(`server`, `tls cert`))
+	assert.False(t, md.IsDefined(`server`, `port`))
+
+	assert.Equal(t, StringType, md.Type(`title`))
+	assert.Equal(t, IntegerType, md.Type(`prot`))
+	assert.Equal(t, InlineTableType, md.Type(`point`))
+	assert.Equal(t, TableType, md.Type(`server`))
+	assert.Equal(t, ArrayOfTablesType, md.Type(`server`, `route`))
+	assert.Equal(t, FloatType, md.Type(`server`, `route`, `timeout`))
+	assert.Equal(t, InvalidType, md.Type(`nope`))
+
+	assert.Equal(t, []Key{
+		{`prot`},
+		{`server`, `tls cert`},
+		{`server`, `route`, `timeout`},
+	}, md.Undecoded())
+}
+
+func TestDecoder_DisallowUnknownFields(t *testing.T) {
+
+	var cfg struct {
+		Title  string
+		Server struct {
+			Host string
+		}
+	}
+
+	dec := NewDecoder(strings.NewReader(`
+title = "x"
+[server]
+host = "localhost"
+prot = 8080`))
+	dec.DisallowUnknownFields()
+
+	_, err := dec.Decode(&cfg)
+	require.Error(t, err)
+	assert.Equal(t, `toml: server.prot (line 5, col 8): unknown field "prot"`, err.Error())
+}
+
+func TestDecoder_remain(t *testing.T) {
+
+	var cfg struct {
+		Title string
+		Rest  map[string]interface{} `toml:",remain"`
+	}
+
+	dec := NewDecoder(strings.NewReader(metaDoc))
+	dec.DisallowUnknownFields()
+
+	md, err := dec.Decode(&cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, `x`, cfg.Title)
+	assert.Equal(t, int64(8080), cfg.Rest[`prot`])
+	assert.Equal(t, map[string]interface{}{`x`: int64(1), `y`: int64(2)}, cfg.Rest[`point`])
+	assert.Contains(t, cfg.Rest, `server`)
+	assert.Empty(t, md.Undecoded())
+}
@@ -0,0 +1,98 @@
+package toml
+
+import (
+	"reflect"
+)
+
+// OrderedMap is a table that keeps its keys in the order of
+// the document. Decoding into an OrderedMap stores nested
+// tables as *OrderedMap, arrays as []interface{} and scalars
+// with the types DecodeTree uses. The encoder writes the keys
+// back in the same order, scalars still before tables.
+//
+// The zero OrderedMap is empty and ready to use.
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+var orderedMapType = reflect.TypeOf(OrderedMap{})
+
+// Keys returns the keys in order.
+func (m *OrderedMap) Keys() []string {
+	return m.keys
+}
+
+// Len returns the number of keys.
+func (m *OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// Get returns the value stored at key.
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// Set stores value at key. New keys are appended, existing
+// keys keep their position.
+func (m *OrderedMap) Set(key string, value interface{}) {
+
+	if m.values == nil {
+		m.values = make(map[string]interface{})
+	}
+
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Delete removes key.
+func (m *OrderedMap) Delete(key string) {
+
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+	delete(m.values, key)
+
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// orderedValue is interfaceValue with tables
+// as *OrderedMap.
+func (n *node) orderedValue() interface{} {
+
+	switch {
+	case n.typ.isTable():
+		m := &OrderedMap{}
+		for _, k := range n.keys {
+			m.Set(k, n.fields[k].orderedValue())
+		}
+		return m
+
+	case n.typ.isArray():
+		arr := make([]interface{}, len(n.items))
+		for i, item := range n.items {
+			arr[i] = item.orderedValue()
+		}
+		return arr
+	}
+	return n.value
+}
+
+func (d *Decoder) decodeOrderedMap(n *node, rv reflect.Value, path keyPath) error {
+
+	if !n.typ.isTable() {
+		return mismatch(n, rv, path)
+	}
+
+	n.markDecoded()
+	rv.Set(reflect.ValueOf(n.orderedValue()).Elem())
+	return nil
+}
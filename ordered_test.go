@@ -0,0 +1,96 @@
+package toml
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedMap(t *testing.T) {
+
+	doc := `zeta = 1
+alpha = "a"
+mid = [3, 1, 2]
+
+[server]
+port = 8080
+host = "localhost"
+
+[server.tls]
+key = "k.pem"
+cert = "c.pem"
+
+[[routes]]
+path = "/b"
+method = "GET"
+
+[[routes]]
+path = "/a"
+
+[routes.inline]
+z = 1
+a = 2
+`
+
+	var m OrderedMap
+	md, err := NewDecoder(bytes.NewReader([]byte(doc))).Decode(&m)
+	require.NoError(t, err)
+	assert.Empty(t, md.Undecoded())
+
+	assert.Equal(t, []string{`zeta`, `alpha`, `mid`, `server`, `routes`}, m.Keys())
+
+	server, ok := m.Get(`server`)
+	require.True(t, ok)
+	assert.Equal(t, []string{`port`, `host`, `tls`}, server.(*OrderedMap).Keys())
+
+	routes, _ := m.Get(`routes`)
+	require.Len(t, routes, 2)
+	assert.Equal(t, []string{`path`, `inline`}, routes.([]interface{})[1].(*OrderedMap).Keys())
+
+	data, err := Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, doc, string(data))
+
+	var st struct {
+		Server *OrderedMap
+	}
+	require.NoError(t, Unmarshal([]byte(doc), &st))
+	assert.Equal(t, 3, st.Server.Len())
+
+	err = Unmarshal([]byte(`server = 1`), &st)
+	assert.EqualError(t, err, `toml: server (line 1, col 10): expected table, found integer 1`)
+
+	// arrays of tables keep their place among tables
+	doc = "a = 1\n\n[[arr]]\nx = 1\n\n[c]\ny = 2\n"
+
+	var mixed OrderedMap
+	require.NoError(t, Unmarshal([]byte(doc), &mixed))
+	assert.Equal(t, []string{`a`, `arr`, `c`}, mixed.Keys())
+
+	data, err = Marshal(mixed)
+	require.NoError(t, err)
+	assert.Equal(t, doc, string(data))
+}
+
+func TestOrderedMap_edit(t *testing.T) {
+
+	var m OrderedMap
+	m.Set(`b`, 1)
+	m.Set(`a`, 2)
+	m.Set(`b`, 3)
+	m.Set(`c`, nil)
+	m.Delete(`x`)
+
+	data, err := Marshal(&m)
+	require.NoError(t, err)
+	assert.Equal(t, "b = 3\na = 2\n", string(data))
+
+	m.Delete(`b`)
+	assert.Equal(t, []string{`a`, `c`}, m.Keys())
+
+	v, ok := m.Get(`a`)
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+}
@@ -0,0 +1,117 @@
+package toml
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+)
+
+// Policy decides how the Encoder and FromJSON handle values
+// TOML has no syntax for. The zero Policy leaves out nil
+// values, writes mixed arrays inline and fails on maps with
+// keys other than strings.
+type Policy struct {
+	// Nil applies to nil pointers and interfaces of Go
+	// values and to JSON null.
+	Nil NilPolicy
+
+	// Sentinel is the string written for nil values
+	// with NilSentinel.
+	Sentinel string
+
+	// Mixed applies to arrays holding tables as well
+	// as other values.
+	Mixed MixedPolicy
+
+	// MapKeys applies to maps with keys other than strings.
+	MapKeys MapKeyPolicy
+}
+
+// NilPolicy selects what happens to nil values.
+type NilPolicy int
+
+const (
+	// NilOmit leaves out keys with nil values. Nil array
+	// elements are dropped, which moves up the elements
+	// after them.
+	NilOmit NilPolicy = iota
+
+	// NilError fails with the path of the nil value.
+	NilError
+
+	// NilSentinel writes Policy.Sentinel as string instead.
+	NilSentinel
+)
+
+// MixedPolicy selects what happens to arrays mixing tables
+// and other values. Such arrays cannot be written as arrays
+// of tables, and only TOML 1.0 allows them as inline arrays.
+type MixedPolicy int
+
+const (
+	// MixedInline writes the array inline, with its tables
+	// as inline tables.
+	MixedInline MixedPolicy = iota
+
+	// MixedError fails with the path of the first element
+	// that does not match the ones before.
+	MixedError
+)
+
+// MapKeyPolicy selects what happens to maps with keys other
+// than strings, which JSON cannot hold.
+type MapKeyPolicy int
+
+const (
+	// MapKeyError fails on such maps.
+	MapKeyError MapKeyPolicy = iota
+
+	// MapKeyString turns keys implementing encoding.TextMarshaler
+	// into their text and integer, float and bool keys into
+	// their decimal representation.
+	MapKeyString
+)
+
+// nilValue returns the value written for a nil value at path
+// and whether anything is written at all.
+func (p Policy) nilValue(path keyPath) (reflect.Value, bool, error) {
+
+	switch p.Nil {
+	case NilError:
+		return reflect.Value{}, false, encodeError(path, `cannot encode nil`)
+	case NilSentinel:
+		return reflect.ValueOf(p.Sentinel), true, nil
+	}
+	return reflect.Value{}, false, nil
+}
+
+// mapKey returns the TOML key for the map key k.
+func (p Policy) mapKey(k reflect.Value, path keyPath) (string, error) {
+
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+
+	if p.MapKeys == MapKeyString {
+
+		if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			if err != nil {
+				return ``, encodeError(path, `%v`, err)
+			}
+			return string(text), nil
+		}
+
+		switch k.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(k.Int(), 10), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return strconv.FormatUint(k.Uint(), 10), nil
+		case reflect.Float32, reflect.Float64:
+			return strconv.FormatFloat(k.Float(), 'g', -1, k.Type().Bits()), nil
+		case reflect.Bool:
+			return strconv.FormatBool(k.Bool()), nil
+		}
+	}
+	return ``, encodeError(path, `cannot encode map with %v keys`, k.Type())
+}
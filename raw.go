@@ -0,0 +1,170 @@
+package toml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// RawValue holds a value of the document as it was written,
+// to decode it later, like json.RawMessage does for JSON.
+// Decoding into a RawValue captures any value, including
+// whole tables with their subtables and arrays of tables.
+//
+//	type Plugin struct {
+//		Name   string
+//		Config toml.RawValue
+//	}
+//
+// Type, Line and Col describe the captured value.
+type RawValue struct {
+	Type Type
+	Line int
+	Col  int
+
+	text []byte
+	n    *node
+	d    Decoder
+	path keyPath
+}
+
+var rawValueType = reflect.TypeOf(RawValue{})
+
+// Bytes returns the TOML text of the value. Tables run from
+// their header to their last key, subtables included. Tables
+// split by keys of other tables, like
+//
+//	[a]
+//	x = 1
+//	[b]
+//	[a.c]
+//
+// have no text of their own, decoding them into a RawValue
+// fails.
+func (r RawValue) Bytes() []byte {
+	return r.text
+}
+
+// Decode stores the value in the value pointed to by v, with
+// the settings of the decoder that captured it. Errors point
+// to the key path and position in the original document.
+func (r RawValue) Decode(v interface{}) error {
+
+	if r.n == nil {
+		return errors.New(`toml: Decode on empty RawValue`)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf(`toml: Decode needs a non-nil pointer, got %T`, v)
+	}
+
+	d := r.d
+	d.src = &source{base: r.n.start, text: r.text}
+	return d.decodeValue(r.n, rv.Elem(), r.path)
+}
+
+func (d *Decoder) decodeRaw(n *node, rv reflect.Value, path keyPath) error {
+
+	text := d.src.slice(n)
+	if text != nil && !d.src.contiguous(n) {
+		return decodeError(path, n, `RawValue of a table split by other keys`)
+	}
+
+	n.markDecoded()
+
+	rv.Set(reflect.ValueOf(RawValue{
+		Type: n.typ,
+		Line: n.pos.Line,
+		Col:  n.pos.Col,
+		text: text,
+		n:    n,
+		d:    *d,
+		path: append(keyPath(nil), path...),
+	}))
+	return nil
+}
+
+// contiguous tells whether the text of the table n holds only
+// its own keys, headers and comments.
+func (s *source) contiguous(n *node) bool {
+
+	if n.typ != TableType && n.typ != ArrayOfTablesType {
+		return true
+	}
+
+	spans := n.spans(nil)
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	pos := n.start
+	for _, span := range spans {
+		if span[0] > pos && !blank(s.text[pos-s.base:span[0]-s.base]) {
+			return false
+		}
+		if span[1] > pos {
+			pos = span[1]
+		}
+	}
+	return true
+}
+
+// spans appends the text ranges of the keys and table
+// headers of n and its children.
+func (n *node) spans(spans [][2]int) [][2]int {
+
+	switch n.typ {
+	case TableType:
+		spans = append(spans, [2]int{n.keyStart, n.end})
+		for _, c := range n.fields {
+			spans = c.spans(spans)
+		}
+	case ArrayOfTablesType:
+		for _, item := range n.items {
+			spans = item.spans(spans)
+		}
+	default:
+		spans = append(spans, [2]int{n.keyStart, n.end})
+	}
+	return spans
+}
+
+// blank tells whether text holds only white space and comments.
+func blank(text []byte) bool {
+
+	for _, line := range bytes.Split(text, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return false
+		}
+	}
+	return true
+}
+
+// holdsRaw tells whether values of type t can contain a
+// RawValue, which needs the text of the document.
+func holdsRaw(t reflect.Type, seen map[reflect.Type]bool) bool {
+
+	if t == rawValueType {
+		return true
+	}
+
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return holdsRaw(t.Elem(), seen)
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if holdsRaw(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
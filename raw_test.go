@@ -0,0 +1,182 @@
+package toml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawValue(t *testing.T) {
+
+	doc := `name = "app"
+port = 8080 # http
+
+[[plugins]]
+kind = "cache"
+
+[plugins.config]
+size = 128
+ttl = "1m"
+
+[plugins.config.evict]
+policy = "lru"
+
+[[plugins]]
+kind = "auth"
+config = { realm = "admin", users = ["a", "b"] }
+`
+
+	type plugin struct {
+		Kind   string
+		Config RawValue
+	}
+
+	var st struct {
+		Name    RawValue
+		Port    RawValue
+		Plugins []plugin
+	}
+
+	md, err := NewDecoder(strings.NewReader(doc)).Decode(&st)
+	require.NoError(t, err)
+	assert.Empty(t, md.Undecoded())
+
+	assert.Equal(t, `"app"`, string(st.Name.Bytes()))
+	assert.Equal(t, StringType, st.Name.Type)
+	assert.Equal(t, `8080`, string(st.Port.Bytes()))
+	assert.Equal(t, 2, st.Port.Line)
+	assert.Equal(t, 8, st.Port.Col)
+
+	require.Len(t, st.Plugins, 2)
+
+	cache := st.Plugins[0].Config
+	assert.Equal(t, TableType, cache.Type)
+	assert.Equal(t, 7, cache.Line)
+	assert.Equal(t, `[plugins.config]
+size = 128
+ttl = "1m"
+
+[plugins.config.evict]
+policy = "lru"`, string(cache.Bytes()))
+
+	var cacheConfig struct {
+		Size  int
+		TTL   string
+		Evict map[string]string
+	}
+	require.NoError(t, cache.Decode(&cacheConfig))
+	assert.Equal(t, 128, cacheConfig.Size)
+	assert.Equal(t, `1m`, cacheConfig.TTL)
+	assert.Equal(t, map[string]string{`policy`: `lru`}, cacheConfig.Evict)
+
+	auth := st.Plugins[1].Config
+	assert.Equal(t, InlineTableType, auth.Type)
+	assert.Equal(t, `{ realm = "admin", users = ["a", "b"] }`, string(auth.Bytes()))
+
+	var authConfig struct {
+		Realm string
+		Users []int
+	}
+	err = auth.Decode(&authConfig)
+
+	var decErr *DecodeError
+	require.True(t, errors.As(err, &decErr))
+	assert.Equal(t, `plugins[1].config.users[0]`, decErr.Path)
+	assert.Equal(t, 16, decErr.Line)
+}
+
+func TestRawValue_nested(t *testing.T) {
+
+	doc := `[server]
+host = "localhost"
+tls = { cert = "a.pem" }
+`
+
+	var st struct {
+		Server RawValue
+	}
+	_, err := NewDecoder(strings.NewReader(doc)).Decode(&st)
+	require.NoError(t, err)
+
+	var server struct {
+		Host string
+		TLS  *RawValue
+	}
+	require.NoError(t, st.Server.Decode(&server))
+	assert.Equal(t, `localhost`, server.Host)
+	require.NotNil(t, server.TLS)
+	assert.Equal(t, `{ cert = "a.pem" }`, string(server.TLS.Bytes()))
+
+	var empty RawValue
+	assert.Error(t, empty.Decode(&server))
+}
+
+func TestRawValue_split(t *testing.T) {
+
+	tests := []struct {
+		doc      string
+		expected string
+		err      string
+	}{
+		{
+			doc: "[a]\nx=1\n[b]\ny=2\n[a.c]\nz=3",
+			err: `toml: a (line 1, col 1): RawValue of a table split by other keys`,
+		},
+		{
+			doc: "a.x=1\nb=5\na.y=2",
+			err: `toml: a (line 1, col 1): RawValue of a table split by other keys`,
+		},
+		{
+			doc: "[[a]]\nx=1\n[b]\n[a.c]\nz=1",
+			err: `toml: a (line 1, col 1): RawValue of a table split by other keys`,
+		},
+		{
+			doc:      "[a]\nx = 1#c [b]\n\n[a.c] # y = 2\n# b = 1\nz = [1,\n2]\n[b]",
+			expected: "[a]\nx = 1#c [b]\n\n[a.c] # y = 2\n# b = 1\nz = [1,\n2]",
+		},
+	}
+
+	for _, test := range tests {
+
+		var st struct {
+			A RawValue
+			B interface{}
+		}
+		_, err := NewDecoder(strings.NewReader(test.doc)).Decode(&st)
+		if test.err != `` {
+			assert.EqualError(t, err, test.err, test.doc)
+			continue
+		}
+		require.NoError(t, err, test.doc)
+		assert.Equal(t, test.expected, string(st.A.Bytes()))
+	}
+}
+
+func TestRawValue_stream(t *testing.T) {
+
+	doc := `[[record]]
+id = 1
+data = { a = 1 }
+
+[[record]]
+id = 2
+data = [1, 2]
+`
+
+	var raws []string
+	err := NewDecoder(strings.NewReader(doc)).DecodeEach(`record`, func(dec *ElementDecoder) error {
+
+		var rec struct {
+			Data RawValue
+		}
+		err := dec.Decode(&rec)
+		raws = append(raws, string(rec.Data.Bytes()))
+		return err
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{ a = 1 }`, `[1, 2]`}, raws)
+}
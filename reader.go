@@ -15,13 +15,131 @@ type Reader struct {
 	readerDone bool
 }
 
+// Option changes the JSON a Reader writes.
+type Option func(o *toml.Options)
+
+// EscapeSlash sets whether / in strings is written as \/.
+// It is on by default.
+func EscapeSlash(on bool) Option {
+	return func(o *toml.Options) {
+		o.KeepSlash = !on
+	}
+}
+
+// EscapeHTML sets whether <, >, & and the line and paragraph
+// separators U+2028 and U+2029 in strings are written as \u
+// escapes, like encoding/json does. It is off by default.
+func EscapeHTML(on bool) Option {
+	return func(o *toml.Options) {
+		o.EscapeHTML = on
+	}
+}
+
+// Tagged sets whether scalars are written as objects holding
+// their TOML type and their value as string, like the JSON of
+// toml-test:
+//
+//	{"type":"datetime-local","value":"1979-05-27T07:32:00"}
+//
+// Tables and arrays stay JSON objects and arrays.
+func Tagged(on bool) Option {
+	return func(o *toml.Options) {
+		o.Tagged = on
+	}
+}
+
+// Indent writes every member of objects and arrays on a line
+// of its own, starting with prefix followed by one indent per
+// nesting level. The output is the same as that of json.Indent,
+// but written while parsing.
+func Indent(prefix, indent string) Option {
+	return func(o *toml.Options) {
+		o.Indented = true
+		o.Prefix = prefix
+		o.Indent = indent
+	}
+}
+
+// InfNaNPolicy selects how the floats inf and nan are written.
+// JSON has no syntax for them.
+type InfNaNPolicy int
+
+const (
+	// InfNaNString writes them as the strings "inf", "+inf",
+	// "-inf", "nan", "+nan" and "-nan", as spelled in the
+	// document. This is the default.
+	InfNaNString = InfNaNPolicy(toml.InfNaNString)
+
+	// InfNaNNull writes them as null.
+	InfNaNNull = InfNaNPolicy(toml.InfNaNNull)
+
+	// InfNaNError makes Read fail on them.
+	InfNaNError = InfNaNPolicy(toml.InfNaNError)
+
+	// InfNaNJSON5 writes them as Infinity, -Infinity and NaN,
+	// which JSON5 and JavaScript accept.
+	InfNaNJSON5 = InfNaNPolicy(toml.InfNaNJSON5)
+)
+
+// InfNaN sets how the floats inf and nan are written. Signed and
+// unsigned forms follow the same policy. Tagged output holds the
+// TOML spelling, only InfNaNError applies to it.
+func InfNaN(policy InfNaNPolicy) Option {
+	return func(o *toml.Options) {
+		o.InfNaN = toml.InfNaN(policy)
+	}
+}
+
+// DateTimeT sets whether date-times written with a space
+// between date and time are written with a T instead.
+func DateTimeT(on bool) Option {
+	return func(o *toml.Options) {
+		o.DateTimeT = on
+	}
+}
+
+// DateTimeUTC sets whether offset date-times are converted
+// to UTC, written with a Z offset.
+func DateTimeUTC(on bool) Option {
+	return func(o *toml.Options) {
+		o.DateTimeUTC = on
+	}
+}
+
+// DateTimePrecision pads or truncates the fractional seconds
+// of date-times and times to digits digits. With 0 they are
+// dropped, with a negative number they stay as written.
+func DateTimePrecision(digits int) Option {
+	return func(o *toml.Options) {
+		o.FixedFrac = digits >= 0
+		o.FracDigits = digits
+	}
+}
+
+// DateTimeEpoch sets whether offset date-times are written as
+// the number of seconds since the Unix epoch, keeping their
+// fractional seconds. Local date-times and times have no
+// instant and stay strings. Tagged output is not affected.
+func DateTimeEpoch(on bool) Option {
+	return func(o *toml.Options) {
+		o.DateTimeEpoch = on
+	}
+}
+
 // New wraps an io.Reader around an io.Reader.
 // Reading data from this Reader reads data from
 // its underlying wrapped io.Reader, parses and
-// encodes it as a JSON stream.
-func New(reader io.Reader) *Reader {
+// encodes it as a JSON stream. Without options
+// the output stays the same across versions.
+func New(reader io.Reader, opts ...Option) *Reader {
+
+	filterOpts := make([]toml.Option, len(opts))
+	for i, opt := range opts {
+		filterOpts[i] = toml.Option(opt)
+	}
+
 	return &Reader{
-		filter: toml.NewFilter(),
+		filter: toml.NewFilter(filterOpts...),
 		reader: reader,
 	}
 }
@@ -29,7 +147,7 @@ func New(reader io.Reader) *Reader {
 func (r *Reader) Read(p []byte) (int, error) {
 
 	if !r.readerDone {
-		for r.filter.State.Buf.Len() < len(p) {
+		for r.filter.State.Output().Len() < len(p) {
 			n, err := r.reader.Read(p)
 			_, err = r.filter.Write(p[:n])
 			if err != nil {
@@ -54,14 +172,15 @@ func (r *Reader) Read(p []byte) (int, error) {
 		r.filter.Close()
 	}
 
-	if r.readerDone && len(r.filter.State.Buf.Bytes()) == 0 {
+	out := r.filter.State.Output()
+	if r.readerDone && len(out.Bytes()) == 0 {
 		if len(r.filter.State.Scopes) != 0 {
 			return 0, fmt.Errorf(`invalid EOF`)
 		}
 		return 0, io.EOF
 	}
 
-	n, err := r.filter.State.Buf.Read(p)
-	r.filter.State.Buf.Truncate(len(r.filter.State.Buf.Bytes()))
+	n, err := out.Read(p)
+	out.Truncate(len(out.Bytes()))
 	return n, err
 }
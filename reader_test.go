@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -204,6 +205,261 @@ func TestReader(t *testing.T) {
 	}
 }
 
+func TestReader_options(t *testing.T) {
+
+	tests := []struct {
+		doc      string
+		opts     []Option
+		expected string
+		err      string
+	}{
+		{
+			doc:      `"a/b" = "x/y<&>"`,
+			expected: `{"a\/b":"x\/y<&>"}`,
+		},
+		{
+			doc:      `"a/b" = "x/y<&>"`,
+			opts:     []Option{EscapeSlash(false)},
+			expected: `{"a/b":"x/y<&>"}`,
+		},
+		{
+			doc:      `'<a>' = '''x/y<&>'''`,
+			opts:     []Option{EscapeHTML(true)},
+			expected: `{"\u003ca\u003e":"x\/y\u003c\u0026\u003e"}`,
+		},
+		{
+			doc:      "a = '\u2028&\u2029'",
+			opts:     []Option{EscapeSlash(false), EscapeHTML(true)},
+			expected: `{"a":"\u2028\u0026\u2029"}`,
+		},
+		{
+			doc:      `a = 'x/y'`,
+			opts:     []Option{EscapeSlash(false), EscapeSlash(true)},
+			expected: `{"a":"x\/y"}`,
+		},
+		{
+			doc:      "a = [1, 2.5, -inf, true]\nb = { c = 1979-05-27, d = 07:32:00 }\ne = 'x'",
+			opts:     []Option{Tagged(true)},
+			expected: `{"a":[{"type":"integer","value":"1"},{"type":"float","value":"2.5"},{"type":"float","value":"-inf"},{"type":"bool","value":"true"}],"b":{"c":{"type":"date-local","value":"1979-05-27"},"d":{"type":"time-local","value":"07:32:00"}},"e":{"type":"string","value":"x"}}`,
+		},
+		{
+			doc:      "[t]\na = 1979-05-27T07:32:00Z\nb = 1979-05-27 07:32:00\nc = 0xff",
+			opts:     []Option{Tagged(true)},
+			expected: `{"t":{"a":{"type":"datetime","value":"1979-05-27T07:32:00Z"},"b":{"type":"datetime-local","value":"1979-05-27 07:32:00"},"c":{"type":"integer","value":"255"}}}`,
+		},
+		{
+			doc:      `a = [inf, +inf, -inf, nan, +nan, -nan]`,
+			expected: `{"a":["inf","+inf","-inf","nan","+nan","-nan"]}`,
+		},
+		{
+			doc:      `a = [inf, +inf, -inf, nan, +nan, -nan]`,
+			opts:     []Option{InfNaN(InfNaNNull)},
+			expected: `{"a":[null,null,null,null,null,null]}`,
+		},
+		{
+			doc:      `a = [inf, +inf, -inf, nan, +nan, -nan]`,
+			opts:     []Option{InfNaN(InfNaNJSON5)},
+			expected: `{"a":[Infinity,Infinity,-Infinity,NaN,NaN,NaN]}`,
+		},
+		{
+			doc:      `a = 1.5e3`,
+			opts:     []Option{InfNaN(InfNaNError)},
+			expected: `{"a":1.5e3}`,
+		},
+		{
+			doc:  `a = inf`,
+			opts: []Option{InfNaN(InfNaNError)},
+			err:  `(0:5) msg: float inf not allowed`,
+		},
+		{
+			doc:  "a = 1\nb = -nan",
+			opts: []Option{InfNaN(InfNaNError)},
+			err:  `(1:6) msg: float -nan not allowed`,
+		},
+		{
+			doc:      `a = [-inf, nan]`,
+			opts:     []Option{InfNaN(InfNaNNull), Tagged(true)},
+			expected: `{"a":[{"type":"float","value":"-inf"},{"type":"float","value":"nan"}]}`,
+		},
+		{
+			doc:  `a = [1.5, -inf]`,
+			opts: []Option{InfNaN(InfNaNError), Tagged(true)},
+			err:  `(0:12) msg: float -inf not allowed`,
+		},
+		{
+			doc:      "a = 1979-05-27 07:32:00.5-07:00\nb = 1979-05-27 07:32:00\nc = 07:32:00\nd = 1979-05-27",
+			expected: `{"a":"1979-05-27 07:32:00.5-07:00","b":"1979-05-27 07:32:00","c":"07:32:00","d":"1979-05-27"}`,
+		},
+		{
+			doc:      "a = 1979-05-27 07:32:00.5-07:00\nb = 1979-05-27 07:32:00\nc = 07:32:00\nd = 1979-05-27",
+			opts:     []Option{DateTimeT(true)},
+			expected: `{"a":"1979-05-27T07:32:00.5-07:00","b":"1979-05-27T07:32:00","c":"07:32:00","d":"1979-05-27"}`,
+		},
+		{
+			doc:      "a = 1979-05-27 20:32:00.5-07:00\nb = 1979-05-27T07:32:00+01:30\nc = 1979-05-27T07:32:00Z\nd = 1979-05-27T07:32:00",
+			opts:     []Option{DateTimeUTC(true)},
+			expected: `{"a":"1979-05-28 03:32:00.5Z","b":"1979-05-27T06:02:00Z","c":"1979-05-27T07:32:00Z","d":"1979-05-27T07:32:00"}`,
+		},
+		{
+			doc:      "a = 1979-05-27T07:32:00.123456-07:00\nb = 1979-05-27T07:32:00\nc = 07:32:00.5\nd = [1979-05-27]",
+			opts:     []Option{DateTimePrecision(3)},
+			expected: `{"a":"1979-05-27T07:32:00.123-07:00","b":"1979-05-27T07:32:00.000","c":"07:32:00.500","d":["1979-05-27"]}`,
+		},
+		{
+			doc:      "a = 1979-05-27T07:32:00.999Z\nb = 07:32:00.5",
+			opts:     []Option{DateTimePrecision(0)},
+			expected: `{"a":"1979-05-27T07:32:00Z","b":"07:32:00"}`,
+		},
+		{
+			doc:      "a = 1979-05-27T07:32:00.25-07:00\nb = 1969-12-31T23:59:59.25Z\nc = 1969-12-31T23:59:59Z\nd = 1979-05-27 07:32:00\ne = 1970-01-01T00:00:00.000Z",
+			opts:     []Option{DateTimeEpoch(true)},
+			expected: `{"a":296663520.25,"b":-0.75,"c":-1,"d":"1979-05-27 07:32:00","e":0.000}`,
+		},
+		{
+			doc:      "a = 1979-05-27 07:32:00.25-07:00",
+			opts:     []Option{DateTimeEpoch(true), DateTimeUTC(true), DateTimePrecision(1), DateTimeT(true), Tagged(true)},
+			expected: `{"a":{"type":"datetime","value":"1979-05-27T14:32:00.2Z"}}`,
+		},
+	}
+
+	for _, ts := range tests {
+
+		t.Log(`doc`, ts.doc)
+
+		data, err := ioutil.ReadAll(New(bytes.NewBufferString(ts.doc), ts.opts...))
+		if ts.err != `` {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), ts.err)
+			continue
+		}
+		require.NoError(t, err)
+		if !strings.Contains(ts.expected, `Infinity`) {
+			// JSON5 is not JSON
+			assert.True(t, json.Valid(data))
+		}
+		assert.Equal(t, ts.expected, string(data))
+	}
+}
+
+func TestReader_taggedChunks(t *testing.T) {
+
+	doc := "a = 1979-05-27T07:32:00.999999-07:00\nb = [123456, 1.5e10, \"long string\"]"
+
+	data, err := ioutil.ReadAll(iotest.OneByteReader(New(iotest.OneByteReader(bytes.NewBufferString(doc)), Tagged(true))))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":{"type":"datetime","value":"1979-05-27T07:32:00.999999-07:00"},"b":[{"type":"integer","value":"123456"},{"type":"float","value":"1.5e10"},{"type":"string","value":"long string"}]}`, string(data))
+}
+
+func TestReader_indent(t *testing.T) {
+
+	doc := "a = []\nb = {}\n[[c]]\nd = [1, { e = 'x' }]\n[[c]]\n[f.g]\nh = 1979-05-27"
+
+	data, err := ioutil.ReadAll(New(bytes.NewBufferString(doc), Indent(`>`, `  `)))
+	require.NoError(t, err)
+	assert.Equal(t, `{
+>  "a": [],
+>  "b": {},
+>  "c": [
+>    {
+>      "d": [
+>        1,
+>        {
+>          "e": "x"
+>        }
+>      ]
+>    },
+>    {}
+>  ],
+>  "f": {
+>    "g": {
+>      "h": "1979-05-27"
+>    }
+>  }
+>}`, string(data))
+
+	data, err = ioutil.ReadAll(New(bytes.NewBufferString(``), Indent(``, "\t")))
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(data))
+}
+
+func TestReader_indentSpecs(t *testing.T) {
+
+	filepath.Walk(`spec-tests/tests/valid`, func(path string, info os.FileInfo, e error) error {
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), `.toml`) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		for _, opts := range [][]Option{nil, {Tagged(true)}} {
+
+			compact, err := io.ReadAll(New(bytes.NewReader(data), opts...))
+			if err != nil {
+				// not parsed, see TestSpecTests_valid
+				return nil
+			}
+
+			expected := &bytes.Buffer{}
+			err = json.Indent(expected, compact, `  `, "\t")
+			require.NoError(t, err, path)
+
+			indented, err := io.ReadAll(New(bytes.NewReader(data), append(opts, Indent(`  `, "\t"))...))
+			require.NoError(t, err, path)
+			assert.Equal(t, expected.String(), string(indented), path)
+		}
+
+		return nil
+	})
+}
+
+func TestReader_dateTimeSpecs(t *testing.T) {
+
+	filepath.Walk(`spec-tests/tests/valid`, func(path string, info os.FileInfo, e error) error {
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), `.toml`) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		_, err = io.ReadAll(New(bytes.NewReader(data)))
+		if err != nil {
+			// not parsed, see TestSpecTests_valid
+			return nil
+		}
+
+		normalized, err := io.ReadAll(New(bytes.NewReader(data), DateTimeT(true), DateTimeUTC(true), DateTimePrecision(3), DateTimeEpoch(true)))
+		require.NoError(t, err, path)
+		assert.True(t, json.Valid(normalized), path)
+		assert.NotRegexp(t, `\d \d|[+-]\d\d:\d\d"|:\d\d"`, string(normalized), path)
+
+		return nil
+	})
+}
+
+func TestReader_defaultOptions(t *testing.T) {
+
+	filepath.Walk(`spec-tests/tests/valid`, func(path string, info os.FileInfo, e error) error {
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), `.toml`) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		plain, plainErr := io.ReadAll(New(bytes.NewReader(data)))
+		opted, optedErr := io.ReadAll(New(bytes.NewReader(data), EscapeSlash(true), EscapeHTML(false)))
+		assert.Equal(t, plainErr, optedErr, path)
+		assert.Equal(t, string(plain), string(opted), path)
+
+		return nil
+	})
+}
+
 func TestSpecs_valid(t *testing.T) {
 
 	var files []string
@@ -397,6 +653,112 @@ func TestSpec_compareJSON(t *testing.T) {
 	})
 }
 
+func TestSpec_compareTaggedJSON(t *testing.T) {
+
+	// These fail for reasons other than the missing types:
+	// lowercase t and z in date-times and a date followed by
+	// a comment are not parsed, \U escapes are kept as text.
+	exclude := map[string]bool{
+		`spec-tests/tests/valid/comment/everywhere.json`:    true,
+		`spec-tests/tests/valid/datetime/datetime.json`:     true,
+		`spec-tests/tests/valid/string/unicode-escape.json`: true,
+	}
+
+	var counter int
+	filepath.Walk(`spec-tests/tests/valid`, func(path string, info os.FileInfo, e error) error {
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), `.json`) || exclude[path] {
+			return nil
+		}
+		counter++
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		var expected interface{}
+		err = json.Unmarshal(data, &expected)
+		require.NoError(t, err)
+
+		data, err = os.ReadFile(path[:len(path)-5] + `.toml`)
+		require.NoError(t, err)
+
+		parsedJSON, err := io.ReadAll(New(bytes.NewReader(data), Tagged(true)))
+		require.NoError(t, err, path)
+
+		var parsed interface{}
+		err = json.Unmarshal(parsedJSON, &parsed)
+		require.NoError(t, err, path)
+
+		require.Equal(t, normalizeTagged(t, expected), normalizeTagged(t, parsed), path)
+
+		return nil
+	})
+	assert.Equal(t, 95, counter)
+}
+
+// normalizeTagged replaces the tagged scalars in obj by their
+// type and a canonical value, so equal values written
+// differently compare equal.
+func normalizeTagged(t *testing.T, obj interface{}) interface{} {
+
+	switch o := obj.(type) {
+	case map[string]interface{}:
+
+		typ, isType := o[`type`].(string)
+		value, isValue := o[`value`].(string)
+		if len(o) == 2 && isType && isValue {
+			return typ + ` ` + normalizeValue(t, typ, value)
+		}
+
+		res := map[string]interface{}{}
+		for key, value := range o {
+			res[key] = normalizeTagged(t, value)
+		}
+		return res
+
+	case []interface{}:
+
+		res := []interface{}{}
+		for _, item := range o {
+			res = append(res, normalizeTagged(t, item))
+		}
+		return res
+	}
+
+	t.Fatalf(`unexpected untagged value %v`, obj)
+	return nil
+}
+
+func normalizeValue(t *testing.T, typ string, value string) string {
+
+	switch typ {
+	case `integer`:
+		i, err := strconv.ParseInt(value, 10, 64)
+		require.NoError(t, err)
+		return strconv.FormatInt(i, 10)
+
+	case `float`:
+		switch value {
+		case `nan`, `-nan`, `+nan`:
+			return `nan`
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		require.NoError(t, err)
+		if f == 0 {
+			// toml-test writes -0.0 as 0
+			f = 0
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64)
+
+	case `datetime`, `datetime-local`, `date-local`, `time-local`:
+		dtyp, v, err := parseDateTime(value)
+		require.NoError(t, err)
+		require.Equal(t, typ, string(dtyp))
+		return formatDateTime(v)
+	}
+	return value
+}
+
 func taggedToUntagged(obj interface{}) (interface{}, error) {
 
 	switch o := obj.(type) {
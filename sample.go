@@ -0,0 +1,156 @@
+package toml
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+)
+
+// SampleOption configures GenerateSample.
+type SampleOption func(e *Encoder)
+
+// WithDocs adds comments for keys without `comment` tag.
+// docs maps dotted keys like "server.port" to their text,
+// keys of arrays of tables have no index.
+func WithDocs(docs map[string]string) SampleOption {
+	return func(e *Encoder) {
+		e.docs = docs
+	}
+}
+
+// GenerateSample writes a sample TOML document for the struct v,
+// for example to keep a config.example.toml in line with the
+// config struct. Every field is written with its value in v,
+// so v would usually hold the defaults. Zero fields with a
+// `default:"..."` tag are written with that default, as Decode
+// sets it. omitempty is ignored.
+//
+// The text of a `comment:"..."` tag becomes # comment lines
+// above the key or table of the field. Nil pointers are written
+// as commented out lines with the zero value they point to, and
+// empty slices of structs as one commented out zero element.
+func GenerateSample(v interface{}, opts ...SampleOption) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	enc := NewEncoder(&buf)
+	enc.sample = true
+	for _, opt := range opts {
+		opt(enc)
+	}
+
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sampleEntry returns the entry for the value v at key.
+// Nil interfaces are left out, their type is unknown.
+func (e *Encoder) sampleEntry(key string, v reflect.Value, comment string, path keyPath) (entry, bool) {
+
+	en := entry{key: key, path: path.key(key), comment: comment}
+	if en.comment == `` {
+		en.comment = e.docs[docKey(en.path)]
+	}
+
+	rv := indirect(v)
+	if !rv.IsValid() {
+		if !v.IsValid() {
+			return en, false
+		}
+
+		t := v.Type()
+		if t.Kind() == reflect.Interface {
+			return en, false
+		}
+
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		rv = reflect.New(t).Elem()
+		en.commented = true
+	}
+
+	if rv.Kind() == reflect.Slice && rv.Len() == 0 {
+
+		t := rv.Type().Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if isTable(reflect.New(t).Elem()) {
+			rv = reflect.MakeSlice(reflect.SliceOf(t), 1, 1)
+			en.commented = true
+		}
+	}
+
+	en.value = rv
+	return en, true
+}
+
+// sampleDefault returns the value of type t the default tag
+// of f sets, like Decode does for keys missing from the
+// document.
+func sampleDefault(f field, t reflect.Type, path keyPath) (reflect.Value, error) {
+
+	rv := reflect.New(t).Elem()
+	err := NewDecoder(nil).decodeDefault(f.defaultValue, rv, path.key(f.name))
+	return rv, err
+}
+
+// docKey returns the dotted key of path without
+// array indexes.
+func docKey(path keyPath) string {
+
+	var key Key
+	for _, e := range path {
+		if e.key != `` {
+			key = append(key, e.key)
+		}
+	}
+	return key.String()
+}
+
+func (e *Encoder) writeComment(buf *bytes.Buffer, comment string, level int) {
+
+	if comment == `` {
+		return
+	}
+
+	for _, line := range strings.Split(comment, "\n") {
+		e.writeIndent(buf, level)
+		buf.WriteString(strings.TrimRight(`# `+line, ` `) + "\n")
+	}
+}
+
+// writeCommented writes what fn writes, as comment if
+// commented is set.
+func (e *Encoder) writeCommented(buf *bytes.Buffer, commented bool, fn func(buf *bytes.Buffer) error) error {
+
+	if !commented || e.commented {
+		return fn(buf)
+	}
+
+	var tmp bytes.Buffer
+
+	e.commented = true
+	err := fn(&tmp)
+	e.commented = false
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.SplitAfter(tmp.String(), "\n") {
+
+		text := strings.TrimLeft(line, " \t")
+		if text == `` || text == "\n" {
+			buf.WriteString(line)
+			continue
+		}
+
+		buf.WriteString(line[:len(line)-len(text)] + `# ` + text)
+	}
+	return nil
+}
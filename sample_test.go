@@ -0,0 +1,114 @@
+package toml
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSample(t *testing.T) {
+
+	type tls struct {
+		Cert string `toml:"cert" comment:"path to the certificate"`
+		Key  string `toml:"key"`
+	}
+
+	type backend struct {
+		Host   string `toml:"host"`
+		Weight *int   `toml:"weight" comment:"defaults to 1"`
+	}
+
+	type server struct {
+		Host    string        `toml:"host" comment:"address to listen on"`
+		Port    int           `toml:"port"`
+		Timeout time.Duration `toml:"timeout"`
+		Limit   *int          `toml:"limit" comment:"requests per second,\nunlimited if not set"`
+		Tags    []string      `toml:"tags,omitempty"`
+		TLS     *tls          `toml:"tls" comment:"enables https"`
+	}
+
+	type config struct {
+		Name     string    `toml:"name"`
+		Server   server    `toml:"server" comment:"the http server"`
+		Backends []backend `toml:"backends"`
+		Extra    interface{}
+	}
+
+	c := config{
+		Name:   `app`,
+		Server: server{Host: `localhost`, Port: 8080, Timeout: 30, Tags: []string{}},
+	}
+
+	data, err := GenerateSample(c, WithDocs(map[string]string{
+		`name`:          `name of the service`,
+		`server.host`:   `ignored, the tag wins`,
+		`backends`:      `upstream servers`,
+		`backends.host`: `host:port`,
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, `# name of the service
+name = "app"
+
+# the http server
+[server]
+# address to listen on
+host = "localhost"
+port = 8080
+timeout = 30
+# requests per second,
+# unlimited if not set
+# limit = 0
+tags = []
+
+# enables https
+# [server.tls]
+# # path to the certificate
+# cert = ""
+# key = ""
+
+# upstream servers
+# [[backends]]
+# # host:port
+# host = ""
+# # defaults to 1
+# weight = 0
+`, string(data))
+
+	var decoded config
+	require.NoError(t, Unmarshal(data, &decoded))
+	assert.Equal(t, c, decoded)
+}
+
+func TestGenerateSample_defaults(t *testing.T) {
+
+	type config struct {
+		Host   string   `toml:"host" default:"localhost"`
+		Port   int      `toml:"port" default:"8080"`
+		Weight *int     `toml:"weight" default:"1"`
+		Tags   []string `toml:"tags" default:"[\"a\", \"b\"]"`
+	}
+
+	data, err := GenerateSample(config{Host: `example.com`})
+	require.NoError(t, err)
+
+	assert.Equal(t, `host = "example.com"
+port = 8080
+weight = 1
+tags = ["a", "b"]
+`, string(data))
+
+	var defaults, decoded config
+	require.NoError(t, Unmarshal([]byte(`host = "example.com"`), &defaults))
+	require.NoError(t, Unmarshal(data, &decoded))
+	assert.Equal(t, defaults, decoded)
+
+	type invalid struct {
+		Port int `toml:"port" default:"x"`
+	}
+
+	_, err = GenerateSample(invalid{})
+	assert.EqualError(t, err, `toml: port: invalid default "x": expected integer, found string "x"`)
+}
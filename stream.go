@@ -0,0 +1,114 @@
+package toml
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ElementDecoder decodes one element of an array of
+// tables handed out by Decoder.DecodeEach.
+type ElementDecoder struct {
+	d     *Decoder
+	n     *node
+	path  keyPath
+	index int
+}
+
+// Index returns the position of the element in its array.
+func (e *ElementDecoder) Index() int {
+	return e.index
+}
+
+// Decode stores the element in the value pointed to by v.
+func (e *ElementDecoder) Decode(v interface{}) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf(`toml: Decode needs a non-nil pointer, got %T`, v)
+	}
+	return e.d.decodeValue(e.n, rv.Elem(), e.path.index(e.index))
+}
+
+// DecodeEach reads the document and calls fn for every element
+// of the array of tables at key, as soon as the element is
+// complete. key is a dotted key like "record" or "fruit.variety".
+//
+// Elements are dropped once fn returns, so arrays of any length
+// decode without holding them in memory. The rest of the document
+// is parsed and checked but not decoded. An error returned by fn
+// stops reading and is returned by DecodeEach.
+func (d *Decoder) DecodeEach(key string, fn func(dec *ElementDecoder) error) error {
+
+	target := strings.Split(key, `.`)
+
+	b := newBuilder()
+	b.src = &source{}
+	d.src = b.src
+
+	var pending []*ElementDecoder
+	counters := make(map[*node]int)
+
+	b.closed = func(key []string) {
+
+		if !equalKeys(key, target) {
+			return
+		}
+
+		// the path holds the index of the current element
+		// of every parent array of tables
+		var path keyPath
+		current := b.root
+		for _, k := range key[:len(key)-1] {
+			path = path.key(k)
+			if parent, ok := current.fields[k]; ok && parent.typ == ArrayOfTablesType && len(parent.items) > 0 {
+				path = path.index(len(parent.items) - 1)
+			}
+			current = current.child(k, current.pos)
+		}
+		path = path.key(key[len(key)-1])
+
+		arr, ok := current.fields[key[len(key)-1]]
+		if !ok || len(arr.items) == 0 {
+			return
+		}
+
+		elem := arr.items[len(arr.items)-1]
+		arr.items = arr.items[:len(arr.items)-1]
+
+		pending = append(pending, &ElementDecoder{d: d, n: elem, path: path, index: counters[arr]})
+		counters[arr]++
+	}
+
+	return b.read(d.reader, func() error {
+
+		for len(pending) > 0 {
+			dec := pending[0]
+			pending = pending[1:]
+
+			err := fn(dec)
+			if err != nil {
+				return err
+			}
+
+			// the text of handed out elements is only
+			// needed for RawValues captured by fn
+			b.src.discard(dec.n.textEnd())
+		}
+		return nil
+	})
+}
+
+func equalKeys(a []string, b []string) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package toml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type record struct {
+	ID   int
+	Name string
+	Tags []string
+}
+
+func TestDecoder_DecodeEach(t *testing.T) {
+
+	doc := `
+title = "records"
+
+[[record]]
+id = 1
+name = "a"
+
+[[record]]
+id = 2
+tags = ["x"]
+
+[[other]]
+id = 3
+
+[[record]]
+id = 4
+
+[meta]
+count = 3
+`
+
+	var records []record
+	var indexes []int
+
+	err := NewDecoder(strings.NewReader(doc)).DecodeEach(`record`, func(dec *ElementDecoder) error {
+
+		var r record
+		err := dec.Decode(&r)
+		if err != nil {
+			return err
+		}
+
+		records = append(records, r)
+		indexes = append(indexes, dec.Index())
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []record{
+		{ID: 1, Name: `a`},
+		{ID: 2, Tags: []string{`x`}},
+		{ID: 4},
+	}, records)
+	assert.Equal(t, []int{0, 1, 2}, indexes)
+}
+
+func TestDecoder_DecodeEachNested(t *testing.T) {
+
+	doc := `
+[[fruit]]
+name = "apple"
+[[fruit.variety]]
+name = "red delicious"
+[[fruit.variety]]
+name = "granny smith"
+
+[[fruit]]
+name = "banana"
+[[fruit.variety]]
+name = "plantain"
+`
+
+	var names []string
+	err := NewDecoder(strings.NewReader(doc)).DecodeEach(`fruit.variety`, func(dec *ElementDecoder) error {
+
+		var v struct{ Name string }
+		err := dec.Decode(&v)
+		names = append(names, v.Name)
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`red delicious`, `granny smith`, `plantain`}, names)
+}
+
+func TestDecoder_DecodeEachErrors(t *testing.T) {
+
+	doc := `
+[[record]]
+id = 1
+[[record]]
+id = "2"
+`
+
+	err := NewDecoder(strings.NewReader(doc)).DecodeEach(`record`, func(dec *ElementDecoder) error {
+		var r record
+		return dec.Decode(&r)
+	})
+	require.Error(t, err)
+	assert.Equal(t, `toml: record[1].id (line 5, col 6): expected integer, found string "2"`, err.Error())
+
+	err = NewDecoder(strings.NewReader(`[[record]]
+id = 1
+id = 2`)).DecodeEach(`record`, func(dec *ElementDecoder) error {
+		return nil
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `attempt to redefine a key`)
+}
+
+func TestDecoder_DecodeEachNestedErrors(t *testing.T) {
+
+	doc := `
+[[fruit]]
+name = "apple"
+[[fruit.variety]]
+name = "red delicious"
+
+[[fruit]]
+name = "banana"
+[[fruit.variety]]
+name = "plantain"
+[[fruit.variety]]
+name = 3
+`
+
+	var paths []string
+	err := NewDecoder(strings.NewReader(doc)).DecodeEach(`fruit.variety`, func(dec *ElementDecoder) error {
+
+		var v struct{ Name string }
+		err := dec.Decode(&v)
+		if err != nil {
+			paths = append(paths, err.(*DecodeError).Path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`fruit[1].variety[1].name`}, paths)
+}
+
+func TestDecoder_DecodeEachStreams(t *testing.T) {
+
+	// the input never ends, so this only stops if the
+	// elements are handed out while reading
+	buf := &TmplBuffer{tmpl: `
+[[record]]
+id = $1
+name = "record $1"
+`}
+
+	errStop := errors.New(`stop`)
+
+	var count int
+	err := NewDecoder(buf).DecodeEach(`record`, func(dec *ElementDecoder) error {
+
+		var r record
+		err := dec.Decode(&r)
+		if err != nil {
+			return err
+		}
+
+		if r.ID != dec.Index() {
+			return errors.New(`out of order`)
+		}
+
+		count++
+		if count == 10000 {
+			return errStop
+		}
+		return nil
+	})
+
+	assert.True(t, errors.Is(err, errStop))
+	assert.Equal(t, 10000, count)
+}
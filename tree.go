@@ -0,0 +1,498 @@
+package toml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	toml "github.com/komkom/toml/internal"
+	"github.com/pkg/errors"
+)
+
+// Type is the TOML type of a value in a document.
+type Type string
+
+var (
+	InvalidType       Type
+	TableType         Type = `table`
+	InlineTableType   Type = `inline-table`
+	ArrayOfTablesType Type = `array-of-tables`
+	ArrayType         Type = `array`
+	StringType        Type = `string`
+	IntegerType       Type = `integer`
+	FloatType         Type = `float`
+	BoolType          Type = `bool`
+	DateTimeType      Type = `datetime`
+	LocalDateTimeType Type = `datetime-local`
+	LocalDateType     Type = `date-local`
+	LocalTimeType     Type = `time-local`
+)
+
+func (t Type) isTable() bool {
+	return t == TableType || t == InlineTableType
+}
+
+func (t Type) isArray() bool {
+	return t == ArrayType || t == ArrayOfTablesType
+}
+
+type node struct {
+	typ    Type
+	value  interface{}
+	keys   []string
+	fields map[string]*node
+	items  []*node
+	pos    toml.Position
+
+	// start and end are the byte offsets of the
+	// value in the document, keyStart the one of its
+	// key or table header.
+	start    int
+	end      int
+	keyStart int
+
+	decoded bool
+}
+
+func newTable(typ Type, pos toml.Position) *node {
+	return &node{typ: typ, fields: make(map[string]*node), pos: pos, start: pos.Offset, end: pos.Offset, keyStart: pos.Offset}
+}
+
+func (n *node) set(key string, child *node) {
+	if _, ok := n.fields[key]; !ok {
+		n.keys = append(n.keys, key)
+	}
+	n.fields[key] = child
+}
+
+// child returns the table stored at key, creating an implicit
+// table if key is undefined. The last element is returned
+// for arrays of tables.
+func (n *node) child(key string, pos toml.Position) *node {
+
+	c, ok := n.fields[key]
+	if !ok {
+		c = newTable(TableType, pos)
+		n.set(key, c)
+	}
+
+	if c.typ == ArrayOfTablesType && len(c.items) > 0 {
+		return c.items[len(c.items)-1]
+	}
+	return c
+}
+
+// textEnd returns the end offset of the value including
+// all of its children.
+func (n *node) textEnd() int {
+
+	end := n.end
+	for _, c := range n.fields {
+		if e := c.textEnd(); e > end {
+			end = e
+		}
+	}
+	for _, item := range n.items {
+		if e := item.textEnd(); e > end {
+			end = e
+		}
+	}
+	return end
+}
+
+func (n *node) markDecoded() {
+
+	n.decoded = true
+	for _, c := range n.fields {
+		c.markDecoded()
+	}
+	for _, item := range n.items {
+		item.markDecoded()
+	}
+}
+
+var (
+	mapType   = reflect.TypeOf(map[string]interface{}{})
+	sliceType = reflect.TypeOf([]interface{}{})
+)
+
+// goType returns the type of interfaceValue without
+// building it.
+func (n *node) goType() reflect.Type {
+
+	switch {
+	case n.typ.isTable():
+		return mapType
+	case n.typ.isArray():
+		return sliceType
+	}
+	return reflect.TypeOf(n.value)
+}
+
+func (n *node) interfaceValue() interface{} {
+
+	switch {
+	case n.typ.isTable():
+		m := make(map[string]interface{}, len(n.keys))
+		for _, k := range n.keys {
+			m[k] = n.fields[k].interfaceValue()
+		}
+		return m
+
+	case n.typ.isArray():
+		arr := make([]interface{}, len(n.items))
+		for i, item := range n.items {
+			arr[i] = item.interfaceValue()
+		}
+		return arr
+	}
+	return n.value
+}
+
+// builder assembles the document tree from the events of
+// the filter.
+type builder struct {
+	root   *node
+	table  *node
+	stack  []*node
+	key    []string
+	keyPos toml.Position
+	err    error
+
+	// closed is called with the key of an array of tables
+	// whose last element is complete.
+	closed func(key []string)
+
+	// src holds the text of the document if it is
+	// needed for RawValues.
+	src *source
+
+	// sink receives the events of the filter instead
+	// of the builder, to pass them on.
+	sink toml.Sink
+}
+
+func newBuilder() *builder {
+	root := newTable(TableType, toml.Position{Line: 1, Col: 1})
+	return &builder{root: root, table: root}
+}
+
+func (b *builder) Table(key []string, v toml.Var, pos toml.Position, end toml.Position) {
+
+	key = unescapeKey(key)
+
+	current := b.root
+	for _, k := range key[:len(key)-1] {
+		current = current.child(k, pos)
+	}
+
+	last := key[len(key)-1]
+
+	if v == toml.ArrayVar {
+		arr, ok := current.fields[last]
+		if !ok {
+			arr = &node{typ: ArrayOfTablesType, pos: pos, start: pos.Offset, keyStart: pos.Offset}
+			current.set(last, arr)
+		}
+
+		b.table = newTable(TableType, pos)
+		b.table.end = end.Offset
+		arr.items = append(arr.items, b.table)
+		return
+	}
+
+	b.table = current.child(last, pos)
+	b.table.pos = pos
+	if end.Offset > b.table.end {
+		b.table.end = end.Offset
+	}
+}
+
+func (b *builder) Key(key []string, pos toml.Position) {
+	b.key = unescapeKey(key)
+	b.keyPos = pos
+}
+
+func (b *builder) Open(kind toml.ValueKind, pos toml.Position) {
+
+	n := &node{typ: ArrayType, pos: pos, start: pos.Offset}
+	if kind == toml.InlineTableKind {
+		n = newTable(InlineTableType, pos)
+	}
+
+	b.insert(n)
+	b.stack = append(b.stack, n)
+}
+
+func (b *builder) Close(kind toml.ValueKind, pos toml.Position) {
+	if len(b.stack) > 0 {
+		b.stack[len(b.stack)-1].end = pos.Offset + 1
+		b.stack = b.stack[:len(b.stack)-1]
+	}
+}
+
+func (b *builder) Value(kind toml.ValueKind, fragment []byte, start toml.Position, end toml.Position) {
+
+	n, err := scalarNode(kind, fragment, start, end)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return
+	}
+
+	b.insert(n)
+}
+
+func scalarNode(kind toml.ValueKind, fragment []byte, start toml.Position, end toml.Position) (*node, error) {
+
+	typ, value, err := scalarValue(kind, fragment)
+	if err != nil {
+		return nil, fmt.Errorf(`position (%v:%v) msg: %v`, start.Line-1, start.Col, err)
+	}
+	return &node{typ: typ, value: value, pos: start, start: start.Offset, end: end.Offset}, nil
+}
+
+func (b *builder) CloseArrayTable(key []string) {
+	if b.closed != nil {
+		b.closed(unescapeKey(key))
+	}
+}
+
+func (b *builder) insert(n *node) {
+
+	if len(b.stack) > 0 && b.stack[len(b.stack)-1].typ == ArrayType {
+		n.keyStart = n.start
+		top := b.stack[len(b.stack)-1]
+		top.items = append(top.items, n)
+		return
+	}
+
+	n.keyStart = b.keyPos.Offset
+	b.parent().set(b.key[len(b.key)-1], n)
+}
+
+// parent returns the table the value of the current key is
+// set in, creating the implicit tables of a dotted key.
+func (b *builder) parent() *node {
+
+	current := b.table
+	if len(b.stack) > 0 {
+		current = b.stack[len(b.stack)-1]
+	}
+
+	for _, k := range b.key[:len(b.key)-1] {
+		current = current.child(k, b.keyPos)
+	}
+	return current
+}
+
+// unescapeKey turns the JSON escaped key segments of the
+// filter back into plain strings.
+func unescapeKey(key []string) []string {
+
+	res := make([]string, len(key))
+	for i, k := range key {
+
+		res[i] = k
+		if !strings.Contains(k, `\`) {
+			continue
+		}
+
+		var s string
+		err := json.Unmarshal([]byte(`"`+k+`"`), &s)
+		if err == nil {
+			res[i] = s
+		}
+	}
+	return res
+}
+
+func scalarValue(kind toml.ValueKind, fragment []byte) (Type, interface{}, error) {
+
+	switch typ := Type(toml.ScalarType(kind, fragment)); typ {
+	case StringType:
+		var s string
+		err := json.Unmarshal(fragment, &s)
+		if err != nil {
+			return InvalidType, nil, errors.Wrap(err, `invalid string`)
+		}
+		return StringType, s, nil
+
+	case BoolType:
+		return BoolType, string(fragment) == `true`, nil
+
+	case DateTimeType, LocalDateTimeType, LocalDateType, LocalTimeType:
+		return parseDateTime(strings.Trim(string(fragment), `"`))
+
+	case FloatType:
+		s := strings.Trim(string(fragment), `"`)
+		if kind == toml.SpecialKind {
+			if strings.HasSuffix(s, `nan`) {
+				return FloatType, math.NaN(), nil
+			}
+			if strings.HasPrefix(s, `-`) {
+				return FloatType, math.Inf(-1), nil
+			}
+			return FloatType, math.Inf(1), nil
+		}
+
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return InvalidType, nil, fmt.Errorf(`invalid float %v`, s)
+		}
+		return FloatType, f, nil
+	}
+
+	s := string(fragment)
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return InvalidType, nil, fmt.Errorf(`integer %v out of range`, s)
+	}
+	return IntegerType, i, nil
+}
+
+// DecodeTree reads the TOML document from r into a tree of
+// generic values. Tables become map[string]interface{} and
+// arrays []interface{}. Scalars keep their TOML type:
+//
+//	integer          int64
+//	float            float64, including ±Inf and NaN
+//	bool             bool
+//	string           string
+//	datetime         time.Time
+//	datetime-local   LocalDateTime
+//	date-local       LocalDate
+//	time-local       LocalTime
+func DecodeTree(r io.Reader) (map[string]interface{}, error) {
+
+	root, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return root.interfaceValue().(map[string]interface{}), nil
+}
+
+// parse reads the whole TOML document from r.
+func parse(r io.Reader) (*node, error) {
+
+	b := newBuilder()
+	err := b.read(r, func() error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return b.root, nil
+}
+
+// read feeds the document from r through the filter into
+// the builder. flush is called after every chunk read.
+func (b *builder) read(r io.Reader, flush func() error) error {
+
+	filter := toml.NewFilter()
+	if b.sink != nil {
+		filter.SetSink(b.sink)
+	} else {
+		filter.SetSink(b)
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if b.src != nil {
+				b.src.text = append(b.src.text, buf[:n]...)
+			}
+
+			_, werr := filter.Write(buf[:n])
+			if werr != nil {
+				return werr
+			}
+			if b.err != nil {
+				return b.err
+			}
+
+			ferr := flush()
+			if ferr != nil {
+				return ferr
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range []rune{'\n', toml.EOF} {
+		err := filter.WriteRune(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.err != nil {
+		return b.err
+	}
+
+	if len(filter.State.Scopes) != 0 {
+		return fmt.Errorf(`invalid EOF`)
+	}
+
+	filter.Close()
+	return flush()
+}
+
+// source holds the text of the document from
+// offset base on.
+type source struct {
+	base int
+	text []byte
+}
+
+// slice returns a copy of the text of n, or nil if it
+// is not held anymore.
+func (s *source) slice(n *node) []byte {
+
+	if s == nil {
+		return nil
+	}
+
+	start, end := n.start-s.base, n.textEnd()-s.base
+	if start < 0 || end > len(s.text) || start > end {
+		return nil
+	}
+
+	text := s.text[start:end]
+	if !n.typ.isTable() && !n.typ.isArray() && n.typ != StringType {
+		// scalars other than strings end at the next
+		// rune, which can be a comment
+		if idx := bytes.IndexByte(text, '#'); idx >= 0 {
+			text = text[:idx]
+		}
+	}
+
+	text = bytes.TrimRight(text, " \t\r\n")
+	return append([]byte(nil), text...)
+}
+
+// discard drops the text before offset.
+func (s *source) discard(offset int) {
+
+	n := offset - s.base
+	if n <= 0 {
+		return
+	}
+	if n > len(s.text) {
+		n = len(s.text)
+	}
+
+	s.text = append([]byte(nil), s.text[n:]...)
+	s.base += n
+}
@@ -0,0 +1,78 @@
+package toml
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeTree(t *testing.T) {
+
+	doc := `
+id = 9223372036854775807
+neg = -9223372036854775808
+bin = 0b1010
+flt = 6.626e-34
+pinf = +inf
+nan = -nan
+yes = true
+str = 'C:\path'
+odt = 1979-05-27T00:32:00Z
+ldt = 1979-05-27T00:32:00
+ld = 1979-05-27
+lt = 00:32:00
+arr = [[1, 2], ["a"], []]
+
+[table]
+inline = {a.b = 1}
+
+[[fruit]]
+name = "apple"
+[[fruit]]
+name = "banana"
+`
+
+	tree, err := DecodeTree(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(math.MaxInt64), tree[`id`])
+	assert.Equal(t, int64(math.MinInt64), tree[`neg`])
+	assert.Equal(t, int64(10), tree[`bin`])
+	assert.Equal(t, 6.626e-34, tree[`flt`])
+	assert.True(t, math.IsInf(tree[`pinf`].(float64), 1))
+	assert.True(t, math.IsNaN(tree[`nan`].(float64)))
+	assert.Equal(t, true, tree[`yes`])
+	assert.Equal(t, `C:\path`, tree[`str`])
+	assert.Equal(t, time.Date(1979, 5, 27, 0, 32, 0, 0, time.UTC), tree[`odt`])
+	assert.Equal(t, LocalDateTime{LocalDate{1979, 5, 27}, LocalTime{0, 32, 0, 0}}, tree[`ldt`])
+	assert.Equal(t, LocalDate{1979, 5, 27}, tree[`ld`])
+	assert.Equal(t, LocalTime{0, 32, 0, 0}, tree[`lt`])
+	assert.Equal(t, []interface{}{
+		[]interface{}{int64(1), int64(2)},
+		[]interface{}{`a`},
+		[]interface{}{},
+	}, tree[`arr`])
+
+	assert.Equal(t, map[string]interface{}{
+		`inline`: map[string]interface{}{
+			`a`: map[string]interface{}{`b`: int64(1)},
+		},
+	}, tree[`table`])
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{`name`: `apple`},
+		map[string]interface{}{`name`: `banana`},
+	}, tree[`fruit`])
+}
+
+func TestDecodeTree_error(t *testing.T) {
+
+	_, err := DecodeTree(strings.NewReader(`a = 1
+a = 2`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `attempt to redefine a key`)
+}
@@ -0,0 +1,241 @@
+package toml
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValidationError lists all fields of a document that failed
+// the checks of their `validate:"..."` tag. Decode checks:
+//
+//	required       the key is present or the field is not zero
+//	min=N, max=N   bounds of numbers, or of the length of
+//	               strings, slices and maps
+//	oneof=a b c    the value is one of the listed ones
+//	pattern=RE     strings match the regular expression;
+//	               pattern comes last as it may hold commas
+//
+// Nil pointers are only checked for required. Only the first
+// failed check of each field is reported.
+//
+// Errors is the supported way to get at the single failures.
+// errors.Is and errors.As only look into them from Go 1.20 on,
+// which follows Unwrap() []error, while this module supports
+// older versions.
+type ValidationError struct {
+	Errors []*DecodeError
+}
+
+func (e *ValidationError) Error() string {
+
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("toml: %v validation errors:\n%v", len(e.Errors), strings.Join(msgs, "\n"))
+}
+
+// Unwrap returns the errors of the single fields, for errors.Is
+// and errors.As from Go 1.20 on.
+func (e *ValidationError) Unwrap() []error {
+
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+type rule struct {
+	name    string
+	bound   float64
+	options []string
+	pattern *regexp.Regexp
+}
+
+func parseRules(tag string) ([]rule, error) {
+
+	var rules []rule
+	for tag != `` {
+
+		var part string
+		if strings.HasPrefix(tag, `pattern=`) {
+			part, tag = tag, ``
+		} else if idx := strings.Index(tag, `,`); idx >= 0 {
+			part, tag = tag[:idx], tag[idx+1:]
+		} else {
+			part, tag = tag, ``
+		}
+
+		name, arg := part, ``
+		if idx := strings.Index(part, `=`); idx >= 0 {
+			name, arg = part[:idx], part[idx+1:]
+		}
+
+		r := rule{name: name}
+
+		switch name {
+		case `required`:
+		case `min`, `max`:
+			bound, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid %v %q`, name, arg)
+			}
+			r.bound = bound
+		case `oneof`:
+			r.options = strings.Fields(arg)
+		case `pattern`:
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return nil, err
+			}
+			r.pattern = re
+		default:
+			return nil, fmt.Errorf(`unknown rule %q`, name)
+		}
+
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// validateStruct checks the fields of the struct rv decoded
+// from the table n. Structs without a table in the document
+// are only checked if their field is required, at the position
+// of n.
+func (d *Decoder) validateStruct(rv reflect.Value, fs fields, present map[string]*node, n *node, path keyPath) {
+
+	for _, f := range fs {
+
+		if f.remain {
+			continue
+		}
+
+		fv := rv.FieldByIndex(f.index)
+		fn := present[f.name]
+
+		pos, key := fn, f.key()
+		if fn == nil {
+			pos = n
+		} else {
+			key = documentKey(n, fn)
+		}
+
+		if f.rulesErr != nil {
+			d.violate(path.key(key), pos, `invalid validate tag: %v`, f.rulesErr)
+			continue
+		}
+
+		// only the first failed check of a field is reported
+		count := len(d.violations)
+		for _, r := range f.rules {
+			d.check(r, fv, fn != nil, path.key(key), pos)
+			if len(d.violations) > count {
+				break
+			}
+		}
+
+		if fn == nil && fv.Kind() == reflect.Struct && f.required() {
+			d.validateStruct(fv, cachedFields(fv.Type()), nil, n, path.key(key))
+		}
+	}
+}
+
+// required tells whether the field has the required rule.
+func (f field) required() bool {
+	for _, r := range f.rules {
+		if r.name == `required` {
+			return true
+		}
+	}
+	return false
+}
+
+// documentKey returns the key of the field node fn in table n.
+func documentKey(n *node, fn *node) string {
+	for _, key := range n.keys {
+		if n.fields[key] == fn {
+			return key
+		}
+	}
+	return ``
+}
+
+func (d *Decoder) check(r rule, rv reflect.Value, present bool, path keyPath, n *node) {
+
+	if r.name == `required` {
+		if !present && rv.IsZero() {
+			d.violate(path, n, `required`)
+		}
+		return
+	}
+
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+
+	switch r.name {
+	case `min`, `max`:
+		value, what, ok := measure(rv)
+		if !ok {
+			d.violate(path, n, `%v needs a number, string, slice or map, got %v`, r.name, rv.Type())
+			return
+		}
+
+		if r.name == `min` && value < r.bound {
+			d.violate(path, n, `%v must be at least %v, got %v`, what, r.bound, value)
+		}
+		if r.name == `max` && value > r.bound {
+			d.violate(path, n, `%v must be at most %v, got %v`, what, r.bound, value)
+		}
+
+	case `oneof`:
+		s := fmt.Sprint(rv.Interface())
+		for _, o := range r.options {
+			if s == o {
+				return
+			}
+		}
+		d.violate(path, n, `must be one of %v, got %q`, strings.Join(r.options, `, `), s)
+
+	case `pattern`:
+		if rv.Kind() != reflect.String {
+			d.violate(path, n, `pattern needs a string, got %v`, rv.Type())
+			return
+		}
+		if !r.pattern.MatchString(rv.String()) {
+			d.violate(path, n, `must match %v, got %q`, r.pattern, rv.String())
+		}
+	}
+}
+
+// measure returns the number min and max compare, which is
+// the length for strings, slices and maps.
+func measure(rv reflect.Value) (float64, string, bool) {
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), `value`, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), `value`, true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), `value`, true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(rv.Len()), `length`, true
+	}
+	return 0, ``, false
+}
+
+func (d *Decoder) violate(path keyPath, n *node, format string, args ...interface{}) {
+	d.violations = append(d.violations, decodeError(path, n, format, args...).(*DecodeError))
+}
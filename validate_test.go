@@ -0,0 +1,98 @@
+package toml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode_validate(t *testing.T) {
+
+	type server struct {
+		Host string `validate:"required,pattern=^[a-z.]+$"`
+		Port int    `validate:"min=1,max=65535"`
+	}
+
+	type config struct {
+		DBName  string   `validate:"required"`
+		Level   string   `validate:"oneof=debug info warn"`
+		Tags    []string `validate:"max=2"`
+		Ratio   *float64 `validate:"min=0,max=1"`
+		Servers []server
+		Backup  server
+		Primary server `validate:"required"`
+	}
+
+	doc := `
+level = "trace"
+tags = ["a", "b", "c"]
+
+[[servers]]
+host = "example.com"
+port = 80
+
+[[servers]]
+host = "Example.com"
+port = 0
+`
+
+	var c config
+	err := Unmarshal([]byte(doc), &c)
+	require.Error(t, err)
+
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+
+	var msgs []string
+	for _, e := range verr.Errors {
+		msgs = append(msgs, e.Error())
+	}
+
+	assert.Equal(t, []string{
+		`toml: servers[1].host (line 10, col 8): must match ^[a-z.]+$, got "Example.com"`,
+		`toml: servers[1].port (line 11, col 8): value must be at least 1, got 0`,
+		`toml: db_name (line 1, col 1): required`,
+		`toml: level (line 2, col 9): must be one of debug, info, warn, got "trace"`,
+		`toml: tags (line 3, col 8): length must be at most 2, got 3`,
+		`toml: primary (line 1, col 1): required`,
+		`toml: primary.host (line 1, col 1): required`,
+		`toml: primary.port (line 1, col 1): value must be at least 1, got 0`,
+	}, msgs)
+	assert.True(t, strings.HasPrefix(err.Error(), "toml: 8 validation errors:\n"))
+
+	valid := `
+db_name = "main"
+level = "info"
+ratio = 0.5
+
+[primary]
+host = "localhost"
+port = 8080
+`
+	c = config{}
+	require.NoError(t, Unmarshal([]byte(valid), &c))
+
+	// missing tables are checked at the position of
+	// their parent table
+	var nested struct {
+		Server struct {
+			Port int
+			TLS  struct {
+				Cert string `validate:"required"`
+			} `validate:"required"`
+		}
+	}
+	err = Unmarshal([]byte("\n[server]\nport = 1\n"), &nested)
+	assert.EqualError(t, err, "toml: 2 validation errors:\n"+
+		"toml: server.tls (line 2, col 1): required\n"+
+		"toml: server.tls.cert (line 2, col 1): required")
+
+	var bad struct {
+		Port int `validate:"min=x"`
+	}
+	err = Unmarshal([]byte(`port = 1`), &bad)
+	assert.EqualError(t, err, `toml: port (line 1, col 8): invalid validate tag: invalid min "x"`)
+}